@@ -0,0 +1,112 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/pkg/session"
+	"sort"
+
+	"github.com/spf13/cobra"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+type ApprovalOptions struct {
+	Session  string
+	Phase    int
+	Reason   string
+	endpoint string
+}
+
+func NewApproveCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var approvalOpts ApprovalOptions
+
+	approveCmd := &cobra.Command{
+		Use:   "approve <session>",
+		Short: "Approve a rollout waiting at a phase gate",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			approvalOpts.Session = args[0]
+			runDecision(approvalOpts, cmd.Flags().Changed("phase"), true, logger)
+		},
+	}
+
+	addApprovalFlags(approveCmd, &approvalOpts)
+
+	return approveCmd
+}
+
+func NewRejectCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var approvalOpts ApprovalOptions
+
+	rejectCmd := &cobra.Command{
+		Use:   "reject <session>",
+		Short: "Reject a rollout waiting at a phase gate, aborting it",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			approvalOpts.Session = args[0]
+			runDecision(approvalOpts, cmd.Flags().Changed("phase"), false, logger)
+		},
+	}
+
+	addApprovalFlags(rejectCmd, &approvalOpts)
+
+	return rejectCmd
+}
+
+func addApprovalFlags(cmd *cobra.Command, opts *ApprovalOptions) {
+	cmd.Flags().IntVar(&opts.Phase, "phase", 0, "Phase to decide on, defaults to the only pending approval")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "Reason recorded with the decision")
+	cmd.Flags().StringVar(&opts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
+}
+
+func runDecision(opts ApprovalOptions, phaseGiven, approved bool, logger *zap.Logger) {
+	sm, err := session.NewEtcdSessionManager([]string{opts.endpoint}, "qtm", "user")
+	if err != nil {
+		logger.Error("Error creating session manager", zap.Error(err))
+		os.Exit(1)
+	}
+	sm.SetSessionID(opts.Session)
+
+	phase, err := resolvePhase(sm, opts.Phase, phaseGiven)
+	if err != nil {
+		fmt.Println("Error finding approval:", err)
+		os.Exit(1)
+	}
+
+	if err := sm.ResolveApproval(phase, approved, opts.Reason); err != nil {
+		fmt.Println("Error recording decision:", err)
+		os.Exit(1)
+	}
+
+	decision := "Rejected"
+	if approved {
+		decision = "Approved"
+	}
+	logger.Info("Approval decided", zap.String("sessionID", opts.Session), zap.Int("phase", phase), zap.Bool("approved", approved))
+	fmt.Printf("%s phase %d of session %s\n", decision, phase, opts.Session)
+}
+
+// resolvePhase returns the requested phase, or the single pending approval if none was given
+func resolvePhase(sm session.SessionManager, phase int, phaseGiven bool) (int, error) {
+	if phaseGiven {
+		return phase, nil
+	}
+
+	approvals, err := sm.GetApprovals()
+	if err != nil {
+		return 0, err
+	}
+
+	pending := session.PendingApprovals(approvals)
+	sort.Ints(pending)
+	switch len(pending) {
+	case 0:
+		return 0, fmt.Errorf("no pending approvals")
+	case 1:
+		return pending[0], nil
+	default:
+		return 0, fmt.Errorf("several phases are waiting for approval %v, use --phase", pending)
+	}
+}
@@ -0,0 +1,212 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/pkg/catalog"
+	"qtm/pkg/values"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+type CatalogOptions struct {
+	Group        string
+	Environment  string
+	Version      string
+	Chart        string
+	ChartVersion string
+	ChartDigest  string
+	Provenance   string
+	valuesFile   string
+	catalogFile  string
+}
+
+// store is a catalog that can be read, listed and written
+type store interface {
+	catalog.CatalogSource
+	catalog.CatalogWriter
+	catalog.CatalogLister
+}
+
+func NewCatalogCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var catalogOpts CatalogOptions
+
+	catalogCmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "Read and update the app catalog",
+	}
+	catalogCmd.PersistentFlags().StringVar(&catalogOpts.Group, "group", "", "Group of the app")
+	catalogCmd.PersistentFlags().StringVar(&catalogOpts.Environment, "env", "", "Environment catalog to use, the global catalog when empty")
+	catalogCmd.PersistentFlags().StringVar(&catalogOpts.catalogFile, "catalog-file", "", "Use a local catalog file instead of etcd")
+
+	getCmd := &cobra.Command{
+		Use:   "get <app>",
+		Short: "Show the catalog entry of an app",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runGet(openStore(catalogOpts, etcdClient), args[0], catalogOpts)
+		},
+	}
+	getCmd.Flags().StringVar(&catalogOpts.Version, "version", "", "Version or semver constraint to look up in the history of the app")
+
+	setCmd := &cobra.Command{
+		Use:   "set <app>",
+		Short: "Create or update the catalog entry of an app",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runSet(openStore(catalogOpts, etcdClient), args[0], catalogOpts, logger)
+		},
+	}
+	setCmd.Flags().StringVar(&catalogOpts.Version, "version", "", "Version of the app")
+	setCmd.Flags().StringVar(&catalogOpts.Chart, "chart", "", "Chart reference: local path, repo/chart or oci://")
+	setCmd.Flags().StringVar(&catalogOpts.ChartVersion, "chart-version", "", "Chart version or constraint for repo and oci charts")
+	setCmd.Flags().StringVar(&catalogOpts.ChartDigest, "chart-digest", "", "Approved SHA-256 digest of the chart archive")
+	setCmd.Flags().StringVar(&catalogOpts.Provenance, "provenance", "", "Path or URL of the chart's provenance file")
+	setCmd.Flags().StringVar(&catalogOpts.valuesFile, "values", "", "Helm values file stored with the entry")
+	setCmd.MarkFlagRequired("version")
+	setCmd.MarkFlagRequired("chart")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List catalog entries, optionally limited to a group",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runList(openStore(catalogOpts, etcdClient), catalogOpts)
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <app>",
+		Short: "Remove the catalog entry of an app",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDelete(openStore(catalogOpts, etcdClient), args[0], catalogOpts, logger)
+		},
+	}
+
+	historyCmd := &cobra.Command{
+		Use:   "history <app>",
+		Short: "Show every recorded change to the catalog entry of an app",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runHistory(openStore(catalogOpts, etcdClient), args[0], catalogOpts)
+		},
+	}
+
+	catalogCmd.AddCommand(getCmd, setCmd, listCmd, deleteCmd, historyCmd)
+	return catalogCmd
+}
+
+func openStore(opts CatalogOptions, etcdClient *clientv3.Client) store {
+	if opts.catalogFile == "" {
+		if opts.Environment != "" {
+			if err := catalog.ValidateEnvironment(opts.Environment); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		return catalog.NewRemoteCatalogSource(etcdClient, catalog.EnvironmentPrefix("qtm", opts.Environment))
+	}
+
+	fc, err := catalog.NewFileCatalogSource(opts.catalogFile)
+	if err != nil {
+		fmt.Println("Error reading catalog file:", err)
+		os.Exit(1)
+	}
+	return fc
+}
+
+func runGet(s store, app string, opts CatalogOptions) {
+	item, err := s.FetchData(app, opts.Group, opts.Version)
+	if err != nil {
+		fmt.Println("Error reading catalog entry:", err)
+		os.Exit(1)
+	}
+
+	data, err := yaml.Marshal(item)
+	if err != nil {
+		fmt.Println("Error encoding catalog entry:", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(data))
+}
+
+func runSet(s store, app string, opts CatalogOptions, logger *zap.Logger) {
+	item := catalog.CatalogItem{
+		Name:         app,
+		Group:        opts.Group,
+		Version:      opts.Version,
+		HelmChart:    opts.Chart,
+		ChartVersion: opts.ChartVersion,
+		ChartDigest:  opts.ChartDigest,
+		Provenance:   opts.Provenance,
+	}
+	if opts.valuesFile != "" {
+		vals, err := values.ReadFile(opts.valuesFile)
+		if err != nil {
+			fmt.Println("Error reading values:", err)
+			os.Exit(1)
+		}
+		item.Values = vals
+	}
+
+	if err := s.PutData(item); err != nil {
+		fmt.Println("Error writing catalog entry:", err)
+		os.Exit(1)
+	}
+	logger.Info("Catalog entry written", zap.String("app", app), zap.String("group", opts.Group), zap.String("version", opts.Version))
+}
+
+func runList(s store, opts CatalogOptions) {
+	items, err := s.ListData(opts.Group)
+	if err != nil {
+		fmt.Println("Error listing catalog:", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tAPP\tVERSION\tCHART")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Group, item.Name, item.Version, item.HelmChart)
+	}
+	w.Flush()
+}
+
+func runDelete(s store, app string, opts CatalogOptions, logger *zap.Logger) {
+	if err := s.DeleteData(app, opts.Group); err != nil {
+		fmt.Println("Error deleting catalog entry:", err)
+		os.Exit(1)
+	}
+	logger.Info("Catalog entry deleted", zap.String("app", app), zap.String("group", opts.Group))
+}
+
+func runHistory(s store, app string, opts CatalogOptions) {
+	h, ok := s.(catalog.CatalogHistory)
+	if !ok {
+		fmt.Println("Error reading catalog history: catalog files do not keep history")
+		os.Exit(1)
+	}
+
+	entries, err := h.History(app, opts.Group)
+	if err != nil {
+		fmt.Println("Error reading catalog history:", err)
+		os.Exit(1)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No history recorded for %s\n", app)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTION\tVERSION\tCHART")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339), entry.Action, entry.Item.Version, entry.Item.HelmChart)
+	}
+	w.Flush()
+}
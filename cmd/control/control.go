@@ -0,0 +1,104 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/pkg/session"
+
+	"github.com/spf13/cobra"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+type ControlOptions struct {
+	Session    string
+	NoRollback bool
+	endpoint   string
+}
+
+func NewPauseCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var controlOpts ControlOptions
+
+	pauseCmd := &cobra.Command{
+		Use:   "pause <session>",
+		Short: "Pause a running rollout at the next app or phase boundary",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			controlOpts.Session = args[0]
+			runControl(controlOpts, session.ControlPause, logger)
+		},
+	}
+
+	pauseCmd.Flags().StringVar(&controlOpts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
+
+	return pauseCmd
+}
+
+func NewResumeCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var controlOpts ControlOptions
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume <session>",
+		Short: "Resume a paused rollout",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			controlOpts.Session = args[0]
+			runControl(controlOpts, session.ControlResume, logger)
+		},
+	}
+
+	resumeCmd.Flags().StringVar(&controlOpts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
+
+	return resumeCmd
+}
+
+func NewAbortCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var controlOpts ControlOptions
+
+	abortCmd := &cobra.Command{
+		Use:   "abort <session>",
+		Short: "Abort a running rollout at the next app or phase boundary",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			controlOpts.Session = args[0]
+			command := session.ControlAbort
+			if controlOpts.NoRollback {
+				command = session.ControlAbortNoRollback
+			}
+			runControl(controlOpts, command, logger)
+		},
+	}
+
+	abortCmd.Flags().BoolVar(&controlOpts.NoRollback, "no-rollback", false, "Leave the apps deployed so far in place instead of rolling them back")
+	abortCmd.Flags().StringVar(&controlOpts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
+
+	return abortCmd
+}
+
+func runControl(opts ControlOptions, command session.ControlCommand, logger *zap.Logger) {
+	sm, err := session.NewEtcdSessionManager([]string{opts.endpoint}, "qtm", "user")
+	if err != nil {
+		logger.Error("Error creating session manager", zap.Error(err))
+		os.Exit(1)
+	}
+	sm.SetSessionID(opts.Session)
+
+	exists, err := sm.ValidateSession()
+	if err != nil {
+		fmt.Println("Error validating session:", err)
+		os.Exit(1)
+	}
+	if !exists {
+		fmt.Println("Session not found:", opts.Session)
+		os.Exit(1)
+	}
+
+	if err := sm.SetControl(command); err != nil {
+		fmt.Println("Error sending control command:", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Control command sent", zap.String("sessionID", opts.Session), zap.String("command", string(command)))
+	fmt.Printf("Sent %s to session %s\n", command, opts.Session)
+}
@@ -0,0 +1,155 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/internal/helmenv"
+	"qtm/pkg/catalog"
+	"qtm/pkg/charts"
+	"qtm/pkg/diff"
+	"qtm/pkg/suite"
+	"qtm/pkg/values"
+
+	"github.com/spf13/cobra"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+type DiffOptions struct {
+	Suite       string
+	Namespace   string
+	suiteFile   string
+	catalogFile string
+	env         string
+	valueFiles  []string
+}
+
+func NewDiffCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var diffOpts DiffOptions
+
+	diffCmd := &cobra.Command{
+		Use:   "diff <suite>",
+		Short: "Diff the rendered charts of a suite against the deployed Helm releases",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			diffOpts.Suite = args[0]
+			runDiff(ctx, diffOpts, etcdClient, logger)
+		},
+	}
+
+	diffCmd.Flags().StringVar(&diffOpts.Namespace, "namespace", "", "Namespace of the Helm releases")
+	diffCmd.Flags().StringVar(&diffOpts.suiteFile, "suite-file", "", "Use local file for suite data")
+	diffCmd.Flags().StringVar(&diffOpts.catalogFile, "catalog-file", "", "Use local file for catalog data")
+	diffCmd.Flags().StringVar(&diffOpts.env, "env", "", "Environment overlay to apply to the suite, also reading the catalog from env:<name> over global unless a catalog file is given")
+	diffCmd.Flags().StringArrayVar(&diffOpts.valueFiles, "values", nil, "Environment Helm values file, merged over catalog, suite and item values (repeatable)")
+
+	return diffCmd
+}
+
+func runDiff(ctx context.Context, opts DiffOptions, etcdClient *clientv3.Client, logger *zap.Logger) {
+	suiteSource, catalogSource, err := initializeSources(opts, etcdClient)
+	if err != nil {
+		fmt.Println("Error initializing sources:", err)
+		os.Exit(1)
+	}
+
+	s, err := suiteSource.FetchSuite()
+	if err != nil {
+		fmt.Println("Error reading data:", err)
+		os.Exit(1)
+	}
+
+	config, namespace, err := helmenv.NewConfiguration(opts.Namespace, logger)
+	if err != nil {
+		fmt.Println("Error initializing helm:", err)
+		os.Exit(1)
+	}
+	differ := diff.NewDiffer(config, namespace)
+
+	resolver, err := helmenv.NewChartResolver()
+	if err != nil {
+		fmt.Println("Error initializing chart resolver:", err)
+		os.Exit(1)
+	}
+
+	layers := values.Layers{Suite: s.Source, Environment: values.Source{Files: opts.valueFiles}}
+	suiteData := suite.OrganizeSuiteData(s)
+	failed := false
+	for _, phase := range suite.SortedPhases(suiteData) {
+		for _, item := range suiteData[phase] {
+			if err := diffApp(ctx, differ, resolver, catalogSource, layers, item); err != nil {
+				fmt.Printf("Error diffing %s: %v\n", item.Name, err)
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func diffApp(ctx context.Context, differ *diff.Differ, resolver *charts.Resolver, catalogSource catalog.CatalogSource, layers values.Layers, item suite.SuiteItem) error {
+	data, err := catalogSource.FetchData(item.Name, item.Group, item.Version)
+	if err != nil {
+		return err
+	}
+
+	vals, err := layers.For(data.Source, item.Source)
+	if err != nil {
+		return err
+	}
+
+	resolved, err := resolver.Resolve(ctx, data.HelmChart, data.ChartVersion)
+	if err != nil {
+		return err
+	}
+	chrt, err := diff.LoadChart(resolved.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load chart %s: %w", data.HelmChart, err)
+	}
+
+	diffs, err := differ.Diff(item.Name, chrt, vals)
+	if err != nil {
+		return err
+	}
+	return diff.Write(os.Stdout, item.Name, diffs)
+}
+
+func initializeSources(opts DiffOptions, etcdClient *clientv3.Client) (suite.SuiteSource, catalog.CatalogSource, error) {
+	var suiteSource suite.SuiteSource
+	var catalogSource catalog.CatalogSource
+	var err error
+
+	if opts.suiteFile != "" {
+		suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile, suite.WithIncludeLoader(suite.NewIncludeLoader(etcdClient, "qtm")))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		suiteSource = suite.NewRemoteSuiteSource(etcdClient, opts.Suite, "qtm")
+	}
+	if opts.env != "" {
+		suiteSource, err = suite.NewEnvironmentSource(suiteSource, opts.env)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if opts.catalogFile != "" {
+		catalogSource, err = catalog.NewFileCatalogSource(opts.catalogFile)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if opts.env != "" {
+		catalogSource, err = catalog.OpenLayers([]string{"env:" + opts.env, "global"}, etcdClient, "qtm", nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		catalogSource = catalog.NewRemoteCatalogSource(etcdClient, "qtm")
+	}
+
+	return suiteSource, catalogSource, nil
+}
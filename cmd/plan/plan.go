@@ -0,0 +1,167 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/internal/helmenv"
+	"qtm/pkg/catalog"
+	"qtm/pkg/plan"
+	"qtm/pkg/session"
+	"qtm/pkg/suite"
+
+	"github.com/spf13/cobra"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+type PlanOptions struct {
+	Suite       string
+	Session     string
+	Namespace   string
+	Output      string
+	UseMockData bool
+	suiteFile   string
+	catalogFile string
+	catalogs    []string
+	env         string
+	endpoint    string
+}
+
+func NewPlanCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var planOpts PlanOptions
+
+	planCmd := &cobra.Command{
+		Use:   "plan <suite>",
+		Short: "Show the actions a rollout of a suite would take",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			planOpts.Suite = args[0]
+			runPlan(planOpts, etcdClient, logger)
+		},
+	}
+
+	planCmd.Flags().StringVar(&planOpts.Session, "session", "", "Compare against the apps recorded in this session")
+	planCmd.Flags().StringVar(&planOpts.Namespace, "namespace", "", "Namespace to read Helm releases from")
+	planCmd.Flags().StringVarP(&planOpts.Output, "output", "o", "text", "Output format: text or json")
+	planCmd.Flags().BoolVar(&planOpts.UseMockData, "mock", false, "Use mock data for testing")
+	planCmd.Flags().StringVar(&planOpts.suiteFile, "suite-file", "", "Use local file for suite data")
+	planCmd.Flags().StringVar(&planOpts.catalogFile, "catalog-file", "", "Use local file for catalog data")
+	planCmd.Flags().StringArrayVar(&planOpts.catalogs, "catalog", nil, "Catalog layer to read apps from, highest precedence first: file:<path>, env:<name> or global (repeatable)")
+	planCmd.Flags().StringVar(&planOpts.env, "env", "", "Environment overlay to apply to the suite, also reading the catalog from env:<name> over global unless a catalog file is given")
+	planCmd.Flags().StringVar(&planOpts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
+
+	return planCmd
+}
+
+func runPlan(opts PlanOptions, etcdClient *clientv3.Client, logger *zap.Logger) {
+	if opts.Output != "text" && opts.Output != "json" {
+		fmt.Println("Error: unknown output format", opts.Output)
+		os.Exit(1)
+	}
+
+	suiteSource, catalogSource, err := initializeSources(opts, etcdClient)
+	if err != nil {
+		fmt.Println("Error initializing sources:", err)
+		os.Exit(1)
+	}
+
+	s, err := suiteSource.FetchSuite()
+	if err != nil {
+		fmt.Println("Error reading data:", err)
+		os.Exit(1)
+	}
+	if s.Name == "" {
+		s.Name = opts.Suite
+	}
+
+	var sessionApps map[string]session.AppData
+	if opts.Session != "" {
+		sm, err := session.NewEtcdSessionManager([]string{opts.endpoint}, "qtm", "user")
+		if err != nil {
+			fmt.Println("Error creating session manager:", err)
+			os.Exit(1)
+		}
+		sm.SetSessionID(opts.Session)
+		sessionApps, err = sm.GetApps()
+		if err != nil {
+			fmt.Println("Error reading session apps:", err)
+			os.Exit(1)
+		}
+	}
+
+	// Mock data has no matching releases, so only compare with Helm for real suites
+	var releases plan.ReleaseSource
+	if !opts.UseMockData {
+		config, _, err := helmenv.NewConfiguration(opts.Namespace, logger)
+		if err != nil {
+			fmt.Println("Error initializing helm:", err)
+			os.Exit(1)
+		}
+		releases = plan.NewHelmReleases(config)
+	}
+
+	p, err := plan.Build(s, catalogSource, sessionApps, releases)
+	if err != nil {
+		fmt.Println("Error building plan:", err)
+		os.Exit(1)
+	}
+
+	if opts.Output == "json" {
+		err = p.RenderJSON(os.Stdout)
+	} else {
+		err = p.Render(os.Stdout)
+	}
+	if err != nil {
+		fmt.Println("Error writing plan:", err)
+		os.Exit(1)
+	}
+}
+
+func initializeSources(opts PlanOptions, etcdClient *clientv3.Client) (suite.SuiteSource, catalog.CatalogSource, error) {
+	if opts.UseMockData {
+		return suite.NewMockSuiteSource(), catalog.NewMockCatalogSource(), nil
+	}
+
+	var suiteSource suite.SuiteSource
+	var catalogSource catalog.CatalogSource
+	var err error
+
+	if opts.suiteFile != "" {
+		suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile, suite.WithIncludeLoader(suite.NewIncludeLoader(etcdClient, "qtm")))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		suiteSource = suite.NewRemoteSuiteSource(etcdClient, opts.Suite, "qtm")
+	}
+	if opts.env != "" {
+		suiteSource, err = suite.NewEnvironmentSource(suiteSource, opts.env)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	specs := opts.catalogs
+	if len(specs) == 0 && opts.catalogFile == "" && opts.env != "" {
+		specs = []string{"env:" + opts.env, "global"}
+	}
+	if len(specs) > 0 {
+		if opts.catalogFile != "" {
+			specs = append([]string{"file:" + opts.catalogFile}, specs...)
+		}
+		catalogSource, err = catalog.OpenLayers(specs, etcdClient, "qtm", nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if opts.catalogFile != "" {
+		catalogSource, err = catalog.NewFileCatalogSource(opts.catalogFile)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		catalogSource = catalog.NewRemoteCatalogSource(etcdClient, "qtm")
+	}
+
+	return suiteSource, catalogSource, nil
+}
@@ -0,0 +1,144 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/pkg/catalog"
+	"qtm/pkg/promote"
+	"qtm/pkg/session"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+type PromoteOptions struct {
+	From     string
+	To       string
+	Group    string
+	Session  string
+	DryRun   bool
+	endpoint string
+}
+
+func NewPromoteCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var promoteOpts PromoteOptions
+
+	promoteCmd := &cobra.Command{
+		Use:   "promote [apps...]",
+		Short: "Copy catalog entries from one environment to another",
+		Long: "Copy the version, chart and values of catalog entries from one environment to another. " +
+			"Every app of the source environment is promoted unless apps are named or a session is given.",
+		Run: func(cmd *cobra.Command, args []string) {
+			runPromote(promoteOpts, args, etcdClient, logger)
+		},
+	}
+
+	promoteCmd.Flags().StringVar(&promoteOpts.From, "from", "", "Environment to promote from")
+	promoteCmd.Flags().StringVar(&promoteOpts.To, "to", "", "Environment to promote to")
+	promoteCmd.Flags().StringVar(&promoteOpts.Group, "group", "", "Only promote apps of this group")
+	promoteCmd.Flags().StringVar(&promoteOpts.Session, "session", "", "Only promote apps successfully rolled out in this session")
+	promoteCmd.Flags().BoolVar(&promoteOpts.DryRun, "dry-run", false, "Show what would be promoted without changing anything")
+	promoteCmd.Flags().StringVar(&promoteOpts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
+	promoteCmd.MarkFlagRequired("from")
+	promoteCmd.MarkFlagRequired("to")
+
+	return promoteCmd
+}
+
+func runPromote(opts PromoteOptions, apps []string, etcdClient *clientv3.Client, logger *zap.Logger) {
+	for _, env := range []string{opts.From, opts.To} {
+		if err := catalog.ValidateEnvironment(env); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	if opts.From == opts.To {
+		fmt.Println("Error: cannot promote an environment to itself")
+		os.Exit(1)
+	}
+
+	from := catalog.NewRemoteCatalogSource(etcdClient, catalog.EnvironmentPrefix("qtm", opts.From))
+	to := catalog.NewRemoteCatalogSource(etcdClient, catalog.EnvironmentPrefix("qtm", opts.To))
+
+	var sessionApps map[string]session.AppData
+	if opts.Session != "" {
+		sm, err := session.NewEtcdSessionManager([]string{opts.endpoint}, "qtm", "user")
+		if err != nil {
+			fmt.Println("Error creating session manager:", err)
+			os.Exit(1)
+		}
+		sm.SetSessionID(opts.Session)
+		sessionApps, err = sm.GetApps()
+		if err != nil {
+			fmt.Println("Error reading session apps:", err)
+			os.Exit(1)
+		}
+	}
+
+	targets, err := promote.SelectTargets(from, opts.Group, apps, sessionApps)
+	if err != nil {
+		fmt.Println("Error selecting apps:", err)
+		os.Exit(1)
+	}
+	if len(targets) == 0 {
+		fmt.Println("Nothing to promote")
+		return
+	}
+
+	changes, err := promote.Plan(from, to, targets)
+	if err != nil {
+		fmt.Println("Error preparing promotion:", err)
+		os.Exit(1)
+	}
+	if opts.DryRun {
+		var planned []promote.App
+		for _, change := range changes {
+			planned = append(planned, change.App)
+		}
+		printApps(planned)
+		return
+	}
+
+	record := promote.Record{
+		From:    opts.From,
+		To:      opts.To,
+		Session: opts.Session,
+		User:    promote.CurrentUser(),
+		Time:    time.Now().UTC(),
+	}
+	record.Apps, err = promote.Apply(to, changes)
+
+	// Record whatever was promoted, even when a later entry failed
+	if len(record.Apps) > 0 {
+		if rerr := promote.NewEtcdRecorder(etcdClient, "qtm").RecordPromotion(record); rerr != nil {
+			logger.Error("Failed to record promotion", zap.Error(rerr))
+		}
+	}
+	printApps(record.Apps)
+	if err != nil {
+		fmt.Println("Error promoting:", err)
+		os.Exit(1)
+	}
+	logger.Info("Promotion complete", zap.String("from", opts.From), zap.String("to", opts.To), zap.String("user", record.User), zap.Int("apps", len(record.Apps)))
+}
+
+func printApps(apps []promote.App) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tAPP\tFROM\tTO\tCHART")
+	for _, app := range apps {
+		previous := app.PreviousVersion
+		if previous == "" {
+			previous = "-"
+		}
+		version := app.Version
+		if app.Unchanged {
+			version += " (unchanged)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", app.Group, app.Name, previous, version, app.Chart)
+	}
+	w.Flush()
+}
@@ -8,6 +8,7 @@ import (
 	"qtm/pkg/rollback"
 	"qtm/pkg/session"
 	"qtm/pkg/suite"
+	"time"
 
 	"github.com/spf13/cobra"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -22,6 +23,9 @@ type RollbackOptions struct {
 	UseMockData bool
 	suiteFile   string
 	DryRun      bool
+	env         string
+	selector    string
+	only        []string
 	endpoint    string
 }
 
@@ -43,6 +47,9 @@ func NewRollbackCmd(ctx context.Context, etcdClient *clientv3.Client, logger *za
 	rollbackCmd.Flags().BoolVar(&rollbackOpts.UseMockData, "mock", false, "Use mock data for testing")
 	rollbackCmd.Flags().StringVar(&rollbackOpts.suiteFile, "suite-file", "", "Use local file to upload suite data")
 	rollbackCmd.Flags().BoolVar(&rollbackOpts.DryRun, "dry-run", false, "Perform a mock deployment without any real changes")
+	rollbackCmd.Flags().StringVar(&rollbackOpts.env, "env", "", "Environment overlay to apply to the suite")
+	rollbackCmd.Flags().StringVarP(&rollbackOpts.selector, "selector", "l", "", "Only roll back apps whose labels match, e.g. team=payments,tier!=edge")
+	rollbackCmd.Flags().StringSliceVar(&rollbackOpts.only, "only", nil, "Only roll back the named apps")
 	rollbackCmd.Flags().StringVar(&rollbackOpts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
 
 	return rollbackCmd
@@ -86,11 +93,42 @@ func runRollback(ctx context.Context, opts RollbackOptions, etcdClient *clientv3
 		os.Exit(1)
 	}
 
+	filter, err := suite.NewItemFilter(opts.selector, opts.only)
+	if err != nil {
+		fmt.Println("Error parsing --selector:", err)
+		os.Exit(1)
+	}
+	phaseData, err := suite.FilterPhases(suite.OrganizeSuiteData(s), filter)
+	if err != nil {
+		fmt.Println("Error selecting apps:", err)
+		os.Exit(1)
+	}
+	if !filter.IsEmpty() {
+		logger.Info("Partial rollback", zap.String("filter", filter.String()), zap.Strings("apps", suite.AppNames(phaseData)))
+		run := session.PartialRun{Operation: "rollback", Filter: filter.String(), Apps: suite.AppNames(phaseData), StartedAt: time.Now()}
+		if err := sessionManager.AddPartialRun(run); err != nil {
+			logger.Warn("Failed to record partial rollback in session", zap.Error(err))
+		}
+	}
+
 	// Create PhaseInfo from Suite - HACK
-	phaseInfos := lifecycle.CreatePhaseInfoFromSuite(s)
+	phaseInfos := lifecycle.CreatePhaseInfo(phaseData)
 
 	// Call rollback function with the constructed PhaseInfo
-	lifecycle.RollbackAllPhases(ctx, rollbacker, phaseInfos, opts.StopAt, logger)
+	status := lifecycle.RollbackAllPhases(ctx, rollbacker, phaseInfos, opts.StopAt, logger)
+	fmt.Println("Rollback finished with status:", status)
+
+	// Keep the session around while some apps are still deployed
+	if status != rollback.RollbackSuccess && status != rollback.RollbackSkipped {
+		fmt.Println("Session retained as not every app was rolled back:", sessionID)
+		os.Exit(1)
+	}
+
+	// Apps outside a partial rollback are still deployed
+	if !filter.IsEmpty() {
+		fmt.Println("Session retained after partial rollback:", sessionID)
+		return
+	}
 
 	// Remove session
 	err = sessionManager.RemoveSession()
@@ -108,13 +146,19 @@ func initializeRollback(opts RollbackOptions, etcdClient *clientv3.Client, sm se
 		suiteSource = suite.NewMockSuiteSource()
 	} else {
 		if opts.suiteFile != "" {
-			suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile)
+			suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile, suite.WithIncludeLoader(suite.NewIncludeLoader(etcdClient, "qtm")))
 			if err != nil {
 				return nil, err
 			}
 		} else {
 			suiteSource = suite.NewRemoteSuiteSource(etcdClient, opts.Suite, "qtm")
 		}
+		if opts.env != "" {
+			suiteSource, err = suite.NewEnvironmentSource(suiteSource, opts.env)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	var rollbacker rollback.Rollbacker
@@ -3,17 +3,30 @@ package rollout
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"qtm/internal/helmenv"
+	"qtm/internal/signals"
 	"qtm/pkg/catalog"
+	"qtm/pkg/charts"
 	"qtm/pkg/deployment"
+	"qtm/pkg/hooks"
 	"qtm/pkg/lifecycle"
 	"qtm/pkg/rollback"
 	"qtm/pkg/session"
 	"qtm/pkg/suite"
+	"qtm/pkg/values"
+	"qtm/pkg/verify"
+	"sort"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/cli"
+	"k8s.io/client-go/kubernetes"
 )
 
 type RolloutOptions struct {
@@ -29,9 +42,16 @@ type RolloutOptions struct {
 	UseMockData bool
 	suiteFile   string
 	catalogFile string
+	catalogs    []string
+	env         string
 	local       bool
 	endpoint    string
 	NewSession  bool
+	onCancel    string
+	valueFiles  []string
+	selector    string
+	only        []string
+	keyring     string
 }
 
 func NewRolloutCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
@@ -57,8 +77,15 @@ func NewRolloutCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap
 	rolloutCmd.Flags().BoolVar(&rolloutOpts.UseMockData, "mock", false, "Use mock data for testing")
 	rolloutCmd.Flags().StringVar(&rolloutOpts.suiteFile, "suite-file", "", "Use local file to upload suite data")
 	rolloutCmd.Flags().StringVar(&rolloutOpts.catalogFile, "catalog-file", "", "Use local file to upload catalog data")
+	rolloutCmd.Flags().StringArrayVar(&rolloutOpts.catalogs, "catalog", nil, "Catalog layer to read apps from, highest precedence first: file:<path>, env:<name>, index:<repo>=<path or url> or global (repeatable)")
+	rolloutCmd.Flags().StringVar(&rolloutOpts.env, "env", "", "Environment overlay to apply to the suite, also reading the catalog from env:<name> over global unless a catalog is given")
 	rolloutCmd.Flags().StringVar(&rolloutOpts.endpoint, "endpoint", "localhost:2379", "Etcd endpoint")
 	rolloutCmd.Flags().BoolVar(&rolloutOpts.NewSession, "new", false, "Indicates a new session should be created")
+	rolloutCmd.Flags().StringArrayVar(&rolloutOpts.valueFiles, "values", nil, "Environment Helm values file, merged over catalog, suite and item values (repeatable)")
+	rolloutCmd.Flags().StringVarP(&rolloutOpts.selector, "selector", "l", "", "Only roll out apps whose labels match, e.g. team=payments,tier!=edge")
+	rolloutCmd.Flags().StringSliceVar(&rolloutOpts.only, "only", nil, "Only roll out the named apps")
+	rolloutCmd.Flags().StringVar(&rolloutOpts.keyring, "keyring", defaultKeyring(), "Public keyring used to verify chart provenance files")
+	rolloutCmd.Flags().StringVar(&rolloutOpts.onCancel, "on-cancel", string(lifecycle.CancelRollbackPhase), "What to do with deployed apps when interrupted: rollback-phase, rollback-all or leave")
 
 	return rolloutCmd
 }
@@ -76,6 +103,12 @@ func runRollout(ctx context.Context, opts RolloutOptions, etcdClient *clientv3.C
 	fmt.Println("DryRun:", opts.DryRun)
 	fmt.Println("UseMockData:", opts.UseMockData)
 
+	cancelPolicy, err := lifecycle.ParseCancelPolicy(opts.onCancel)
+	if err != nil {
+		fmt.Println("Error parsing --on-cancel:", err)
+		os.Exit(1)
+	}
+
 	sm, err := session.NewEtcdSessionManager([]string{opts.endpoint}, "qtm", "user")
 	if err != nil {
 		logger.Error("Error creating session manager", zap.Error(err))
@@ -86,7 +119,13 @@ func runRollout(ctx context.Context, opts RolloutOptions, etcdClient *clientv3.C
 		logger.Fatal("Session manager is nil, this should not happen")
 	}
 
-	deployer, err := initializeDeployer(opts, etcdClient, sm, logger)
+	resolver, err := helmenv.NewChartResolver()
+	if err != nil {
+		fmt.Println("Error initializing chart resolver:", err)
+		os.Exit(1)
+	}
+
+	deployer, err := initializeDeployer(ctx, opts, etcdClient, sm, resolver, logger)
 	if err != nil {
 		fmt.Println("Error initializing deployer:", err)
 		os.Exit(1)
@@ -110,6 +149,11 @@ func runRollout(ctx context.Context, opts RolloutOptions, etcdClient *clientv3.C
 	sessionManager.RegisterNewSession(sessionID)
 	logger.Info("Session created", zap.String("sessionID", sessionID))
 
+	// Clear any pause or abort left over from an earlier rollout in this session
+	if err := sessionManager.SetControl(session.ControlResume); err != nil {
+		logger.Warn("Failed to reset session control", zap.Error(err))
+	}
+
 	// Fetch data using deployer's suite source
 	suiteSource := deployer.GetSuiteSource()
 	s, err := suiteSource.FetchSuite()
@@ -118,10 +162,30 @@ func runRollout(ctx context.Context, opts RolloutOptions, etcdClient *clientv3.C
 		os.Exit(1)
 	}
 	logger.Debug("Suite data", zap.Any("suite", s))
+	if opts.Namespace == "" {
+		opts.Namespace = s.Namespace
+	}
 
-	suiteData := suite.OrganizeSuiteData(s)
+	filter, err := suite.NewItemFilter(opts.selector, opts.only)
+	if err != nil {
+		fmt.Println("Error parsing --selector:", err)
+		os.Exit(1)
+	}
+	suiteData, err := suite.FilterPhases(suite.OrganizeSuiteData(s), filter)
+	if err != nil {
+		fmt.Println("Error selecting apps:", err)
+		os.Exit(1)
+	}
 	logger.Debug("Organized suite data", zap.Any("suiteData", suiteData))
 
+	if !filter.IsEmpty() {
+		logger.Info("Partial rollout", zap.String("filter", filter.String()), zap.Strings("apps", suite.AppNames(suiteData)))
+		run := session.PartialRun{Operation: "rollout", Filter: filter.String(), Apps: suite.AppNames(suiteData), StartedAt: time.Now()}
+		if err := sessionManager.AddPartialRun(run); err != nil {
+			logger.Warn("Failed to record partial rollout in session", zap.Error(err))
+		}
+	}
+
 	//Determine if rollback is required by checking atomic and nuclear flags
 	rollbackRequired := opts.Atomic || opts.Nuclear
 	var rollbacker rollback.Rollbacker
@@ -135,17 +199,100 @@ func runRollout(ctx context.Context, opts RolloutOptions, etcdClient *clientv3.C
 		rollbacker = nil
 	}
 
+	// Rollbacks after an interrupt run on their own context, which is only
+	// cancelled if the user forces a quit
+	journal := session.NewJournal(sessionManager)
+	rollbackCtx, cancelRollback := context.WithCancel(context.Background())
+	defer cancelRollback()
+	signals.OnForceQuit(func() {
+		cancelRollback()
+		if err := journal.Flush(); err != nil {
+			fmt.Println("Error flushing session journal:", err)
+		}
+		printLeftDeployed(os.Stdout, journal)
+	})
+
 	// Deploy phases
-	success := lifecycle.DeployAllPhases(ctx, deployer, rollbacker, suiteData, lifecycle.DefaultDecisionMaker, false, logger)
+	kube, namespace := newKubeClient(opts, logger)
+	success := lifecycle.DeployAllPhases(ctx, deployer, rollbacker, suiteData, lifecycle.DefaultDecisionMaker, false, logger,
+		lifecycle.WithPhaseConfigs(s.PhaseConfigs()),
+		lifecycle.WithCheckBuilder(verify.NewBuilder(kube, namespace)),
+		lifecycle.WithHookRunner(hooks.NewRunner(kube, namespace)),
+		lifecycle.WithSuiteHooks(s.Name, s.Hooks),
+		lifecycle.WithCancelPolicy(cancelPolicy),
+		lifecycle.WithRollbackContext(rollbackCtx),
+		lifecycle.WithJournal(journal),
+		lifecycle.WithValueLayers(values.Layers{Suite: s.Source, Environment: values.Source{Files: opts.valueFiles}}),
+		lifecycle.WithChartVerifier(charts.NewVerifier(resolver, opts.keyring)),
+	)
 
 	if success {
 		fmt.Println("Deployment completed successfully")
 	} else {
 		fmt.Println("Deployment failed or was cancelled")
 	}
+
+	if err := printSessionReport(os.Stdout, sessionManager); err != nil {
+		logger.Error("Error reading session report", zap.Error(err))
+	}
+
+	if !success {
+		printLeftDeployed(os.Stdout, journal)
+	}
+}
+
+// printLeftDeployed prints the apps this rollout deployed and did not roll back
+func printLeftDeployed(out io.Writer, journal *session.Journal) {
+	if stopped, ok := journal.Stopped(); ok {
+		reason := stopped.Message
+		if reason == "" {
+			reason = "cancelled"
+		}
+		fmt.Fprintf(out, "Stopped in phase %d: %s (%s)\n", stopped.Phase, reason, stopped.Status)
+	}
+
+	deployed := journal.Deployed()
+	if len(deployed) == 0 {
+		fmt.Fprintln(out, "Nothing deployed by this rollout was left in place")
+		return
+	}
+
+	fmt.Fprintln(out, "Left deployed by this rollout:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tPHASE\tVERSION")
+	for _, entry := range deployed {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", entry.App, entry.Phase, entry.Version)
+	}
+	w.Flush()
 }
 
-func initializeDeployer(opts RolloutOptions, etcdClient *clientv3.Client, sm session.SessionManager, logger *zap.Logger) (deployment.Deployer, error) {
+// printSessionReport prints the status of every app recorded in the session
+func printSessionReport(out io.Writer, sm session.SessionManager) error {
+	apps, err := sm.GetApps()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(apps))
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tGROUP\tPHASE\tVERSION\tCATALOG\tSTATUS\tROLLBACK")
+	for _, name := range names {
+		app := apps[name]
+		origin := app.Origin
+		if origin == "" {
+			origin = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", name, app.Item.Group, app.Item.RolloutPhase, app.Version, origin, app.Status, app.RollbackStatus)
+	}
+	return w.Flush()
+}
+
+func initializeDeployer(ctx context.Context, opts RolloutOptions, etcdClient *clientv3.Client, sm session.SessionManager, resolver *charts.Resolver, logger *zap.Logger) (deployment.Deployer, error) {
 
 	var catalogSource catalog.CatalogSource
 	var suiteSource suite.SuiteSource
@@ -156,15 +303,35 @@ func initializeDeployer(opts RolloutOptions, etcdClient *clientv3.Client, sm ses
 		suiteSource = suite.NewMockSuiteSource()
 	} else {
 		if opts.suiteFile != "" {
-			suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile)
+			suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile, suite.WithIncludeLoader(suite.NewIncludeLoader(etcdClient, "qtm")))
 			if err != nil {
 				return nil, err
 			}
 		} else {
 			suiteSource = suite.NewRemoteSuiteSource(etcdClient, opts.Suite, "qtm")
 		}
+		if opts.env != "" {
+			suiteSource, err = suite.NewEnvironmentSource(suiteSource, opts.env)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-		if opts.catalogFile != "" {
+		specs := opts.catalogs
+		if len(specs) == 0 && opts.catalogFile == "" && opts.env != "" {
+			specs = []string{"env:" + opts.env, "global"}
+		}
+		if len(specs) > 0 {
+			if opts.catalogFile != "" {
+				specs = append([]string{"file:" + opts.catalogFile}, specs...)
+			}
+			catalogSource, err = catalog.OpenLayers(specs, etcdClient, "qtm", map[string]catalog.LayerOpener{
+				"index": helmenv.IndexOpener(ctx, resolver),
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else if opts.catalogFile != "" {
 			catalogSource, err = catalog.NewFileCatalogSource(opts.catalogFile)
 			if err != nil {
 				return nil, err
@@ -189,6 +356,30 @@ func initializeDeployer(opts RolloutOptions, etcdClient *clientv3.Client, sm ses
 	return deployer, nil
 }
 
+// newKubeClient creates a Kubernetes client for verification checks and job
+// hooks. It returns nil when no Kubernetes configuration can be loaded.
+func newKubeClient(opts RolloutOptions, logger *zap.Logger) (kubernetes.Interface, string) {
+	settings := cli.New()
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = settings.Namespace()
+	}
+
+	restConfig, err := settings.RESTClientGetter().ToRESTConfig()
+	if err != nil {
+		logger.Warn("Kubernetes configuration unavailable, rollout checks and job hooks disabled", zap.Error(err))
+		return nil, namespace
+	}
+
+	kube, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		logger.Warn("Failed to create kubernetes client, rollout checks and job hooks disabled", zap.Error(err))
+		return nil, namespace
+	}
+
+	return kube, namespace
+}
+
 func initializeRollback(opts RolloutOptions, etcdClient *clientv3.Client, sm session.SessionManager, logger *zap.Logger) (rollback.Rollbacker, error) {
 	var suiteSource suite.SuiteSource
 	var err error
@@ -197,13 +388,19 @@ func initializeRollback(opts RolloutOptions, etcdClient *clientv3.Client, sm ses
 		suiteSource = suite.NewMockSuiteSource()
 	} else {
 		if opts.suiteFile != "" {
-			suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile)
+			suiteSource, err = suite.NewFileSuiteSource(opts.suiteFile, suite.WithIncludeLoader(suite.NewIncludeLoader(etcdClient, "qtm")))
 			if err != nil {
 				return nil, err
 			}
 		} else {
 			suiteSource = suite.NewRemoteSuiteSource(etcdClient, opts.Suite, "qtm")
 		}
+		if opts.env != "" {
+			suiteSource, err = suite.NewEnvironmentSource(suiteSource, opts.env)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	var rollbacker rollback.Rollbacker
@@ -218,3 +415,11 @@ func initializeRollback(opts RolloutOptions, etcdClient *clientv3.Client, sm ses
 
 	return rollbacker, nil
 }
+
+// defaultKeyring returns the keyring Helm verifies provenance files with
+func defaultKeyring() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".gnupg", "pubring.gpg")
+	}
+	return ""
+}
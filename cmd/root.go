@@ -2,8 +2,15 @@ package cmd
 
 import (
 	"context"
+	"qtm/cmd/approval"
+	"qtm/cmd/catalog"
+	"qtm/cmd/control"
+	"qtm/cmd/diff"
+	"qtm/cmd/plan"
+	"qtm/cmd/promote"
 	"qtm/cmd/rollback"
 	"qtm/cmd/rollout"
+	"qtm/cmd/suite"
 
 	"github.com/spf13/cobra"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -22,6 +29,16 @@ func NewRootCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Lo
 
 	rootCmd.AddCommand(rollout.NewRolloutCmd(ctx, etcdClient, logger))
 	rootCmd.AddCommand(rollback.NewRollbackCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(approval.NewApproveCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(approval.NewRejectCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(control.NewPauseCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(control.NewResumeCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(control.NewAbortCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(plan.NewPlanCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(diff.NewDiffCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(catalog.NewCatalogCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(promote.NewPromoteCmd(ctx, etcdClient, logger))
+	rootCmd.AddCommand(suite.NewSuiteCmd(ctx, etcdClient, logger))
 
 	rootCmd.Flags().StringVar(&session, "session", "", "String ID to overwrite dynamically made session")
 
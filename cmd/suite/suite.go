@@ -0,0 +1,226 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/internal/helmenv"
+	"qtm/pkg/catalog"
+	"qtm/pkg/suite"
+
+	"github.com/spf13/cobra"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+type SuiteOptions struct {
+	catalogs    []string
+	catalogFile string
+	env         string
+	format      string
+}
+
+func NewSuiteCmd(ctx context.Context, etcdClient *clientv3.Client, logger *zap.Logger) *cobra.Command {
+	var suiteOpts SuiteOptions
+
+	suiteCmd := &cobra.Command{
+		Use:   "suite",
+		Short: "Inspect and check suites",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate <file|name>",
+		Short: "Check a suite file or stored suite and report every problem found",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runValidate(ctx, suiteOpts, args[0], etcdClient, logger)
+		},
+	}
+	validateCmd.Flags().StringArrayVar(&suiteOpts.catalogs, "catalog", nil, "Catalog layer to resolve items against, highest precedence first: file:<path>, env:<name>, index:<repo>=<path or url> or global (repeatable)")
+	validateCmd.Flags().StringVar(&suiteOpts.catalogFile, "catalog-file", "", "Use local file for catalog data")
+	validateCmd.Flags().StringVar(&suiteOpts.env, "env", "", "Environment overlay to check along with the suite, also reading the catalog from env:<name> over global unless a catalog is given")
+
+	renderCmd := &cobra.Command{
+		Use:   "render <file|name>",
+		Short: "Print a suite with its includes and environment overlay resolved",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runRender(suiteOpts, args[0], etcdClient)
+		},
+	}
+	renderCmd.Flags().StringVar(&suiteOpts.env, "env", "", "Environment overlay to apply to the suite")
+
+	graphCmd := &cobra.Command{
+		Use:   "graph <file|name>",
+		Short: "Draw the phases and dependencies of a suite, annotated with catalog versions",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runGraph(ctx, suiteOpts, args[0], etcdClient)
+		},
+	}
+	graphCmd.Flags().StringVar(&suiteOpts.format, "format", suite.GraphDot, "Output format: dot, mermaid or json")
+	graphCmd.Flags().StringVar(&suiteOpts.env, "env", "", "Environment overlay to apply to the suite, also reading the catalog from env:<name> over global unless a catalog is given")
+	graphCmd.Flags().StringArrayVar(&suiteOpts.catalogs, "catalog", nil, "Catalog layer to read versions from, highest precedence first: file:<path>, env:<name>, index:<repo>=<path or url> or global (repeatable)")
+	graphCmd.Flags().StringVar(&suiteOpts.catalogFile, "catalog-file", "", "Use local file for catalog data")
+
+	suiteCmd.AddCommand(validateCmd, renderCmd, graphCmd)
+	return suiteCmd
+}
+
+func runValidate(ctx context.Context, opts SuiteOptions, ref string, etcdClient *clientv3.Client, logger *zap.Logger) {
+	source, origin, data, err := readDocument(ref, etcdClient)
+	if err != nil {
+		fmt.Println("Error reading suite:", err)
+		os.Exit(1)
+	}
+
+	catalogSource, err := openCatalog(ctx, opts, etcdClient)
+	if err != nil {
+		fmt.Println("Error opening catalog:", err)
+		os.Exit(1)
+	}
+	if catalogSource == nil {
+		fmt.Println("No catalog given, catalog entries were not checked")
+	}
+
+	problems := suite.Validate(data, origin, suite.NewIncludeLoader(etcdClient, "qtm"), catalogSource)
+	if opts.env != "" {
+		problems = append(problems, overlayProblems(opts.env, ref, etcdClient, catalogSource)...)
+	}
+	for _, problem := range problems {
+		if problem.Line > 0 {
+			fmt.Printf("%s:%d: %s\n", source, problem.Line, problem.Message)
+		} else {
+			fmt.Printf("%s: %s\n", source, problem.Message)
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problem(s) found\n", len(problems))
+		os.Exit(1)
+	}
+	logger.Info("Suite is valid", zap.String("suite", source))
+}
+
+// overlayProblems checks the overlay of an environment, reporting every
+// problem against the environment
+func overlayProblems(env, ref string, etcdClient *clientv3.Client, catalogSource catalog.CatalogSource) []suite.Problem {
+	var problems []suite.Problem
+	base, err := openSuite(ref, etcdClient)
+	if err != nil {
+		problems = []suite.Problem{{Message: err.Error()}}
+	} else if source, err := suite.NewEnvironmentSource(base, env); err != nil {
+		problems = []suite.Problem{{Message: err.Error()}}
+	} else {
+		problems = suite.ValidateOverlay(source, catalogSource)
+	}
+
+	for i := range problems {
+		problems[i].Message = fmt.Sprintf("environment %s: %s", env, problems[i].Message)
+	}
+	return problems
+}
+
+func runRender(opts SuiteOptions, ref string, etcdClient *clientv3.Client) {
+	source, err := openSuite(ref, etcdClient)
+	if err != nil {
+		fmt.Println("Error reading suite:", err)
+		os.Exit(1)
+	}
+	if opts.env != "" {
+		source, err = suite.NewEnvironmentSource(source, opts.env)
+		if err != nil {
+			fmt.Println("Error reading overlay:", err)
+			os.Exit(1)
+		}
+	}
+
+	s, err := source.FetchSuite()
+	if err != nil {
+		fmt.Println("Error resolving suite:", err)
+		os.Exit(1)
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		fmt.Println("Error encoding suite:", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(data))
+}
+
+func runGraph(ctx context.Context, opts SuiteOptions, ref string, etcdClient *clientv3.Client) {
+	source, err := openSuite(ref, etcdClient)
+	if err != nil {
+		fmt.Println("Error reading suite:", err)
+		os.Exit(1)
+	}
+	if opts.env != "" {
+		source, err = suite.NewEnvironmentSource(source, opts.env)
+		if err != nil {
+			fmt.Println("Error reading overlay:", err)
+			os.Exit(1)
+		}
+	}
+
+	s, err := source.FetchSuite()
+	if err != nil {
+		fmt.Println("Error resolving suite:", err)
+		os.Exit(1)
+	}
+
+	catalogSource, err := openCatalog(ctx, opts, etcdClient)
+	if err != nil {
+		fmt.Println("Error opening catalog:", err)
+		os.Exit(1)
+	}
+
+	if err := suite.WriteGraph(os.Stdout, suite.BuildGraph(s, catalogSource), opts.format); err != nil {
+		fmt.Println("Error writing graph:", err)
+		os.Exit(1)
+	}
+}
+
+// readDocument reads a suite from a file, or from etcd when no file exists at
+// ref. It returns a name to report problems against and the origin of the
+// document for resolving includes.
+func readDocument(ref string, etcdClient *clientv3.Client) (string, string, []byte, error) {
+	if _, err := os.Stat(ref); err == nil {
+		data, err := os.ReadFile(ref)
+		return ref, suite.FileOrigin(ref), data, err
+	}
+
+	data, err := suite.NewRemoteSuiteSource(etcdClient, ref, "qtm").FetchDocument()
+	return "suites/" + ref, "suite:" + ref, data, err
+}
+
+// openSuite opens a suite file, or a suite stored in etcd when no file exists at ref
+func openSuite(ref string, etcdClient *clientv3.Client) (suite.SuiteSource, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return suite.NewFileSuiteSource(ref, suite.WithIncludeLoader(suite.NewIncludeLoader(etcdClient, "qtm")))
+	}
+	return suite.NewRemoteSuiteSource(etcdClient, ref, "qtm"), nil
+}
+
+// openCatalog opens the catalog chosen on the command line, nil when none was
+// chosen. An environment without a catalog reads env:<name> over global.
+func openCatalog(ctx context.Context, opts SuiteOptions, etcdClient *clientv3.Client) (catalog.CatalogSource, error) {
+	specs := opts.catalogs
+	if len(specs) == 0 && opts.catalogFile == "" && opts.env != "" {
+		specs = []string{"env:" + opts.env, "global"}
+	}
+	if opts.catalogFile != "" {
+		specs = append([]string{"file:" + opts.catalogFile}, specs...)
+	}
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	resolver, err := helmenv.NewChartResolver()
+	if err != nil {
+		return nil, err
+	}
+	return catalog.OpenLayers(specs, etcdClient, "qtm", map[string]catalog.LayerOpener{
+		"index": helmenv.IndexOpener(ctx, resolver),
+	})
+}
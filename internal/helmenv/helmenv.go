@@ -0,0 +1,56 @@
+package helmenv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/pkg/catalog"
+	"qtm/pkg/charts"
+	"strings"
+
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// NewConfiguration creates a Helm action configuration from the usual Helm
+// environment (KUBECONFIG, HELM_NAMESPACE, HELM_DRIVER). An empty namespace
+// uses the namespace of the current context. It returns the namespace used.
+func NewConfiguration(namespace string, logger *zap.Logger) (*action.Configuration, string, error) {
+	settings := cli.New()
+	if namespace == "" {
+		namespace = settings.Namespace()
+	}
+
+	config := new(action.Configuration)
+	debug := func(format string, v ...interface{}) {
+		logger.Debug(fmt.Sprintf(format, v...))
+	}
+	if err := config.Init(settings.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"), debug); err != nil {
+		return nil, namespace, err
+	}
+	return config, namespace, nil
+}
+
+// NewChartResolver creates a chart resolver using the repositories configured
+// for Helm and the default chart cache
+func NewChartResolver() (*charts.Resolver, error) {
+	settings := cli.New()
+	repositories, err := charts.LoadRepositories(settings.RepositoryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load helm repositories: %w", err)
+	}
+	return charts.NewResolver(charts.DefaultCacheDir(), repositories), nil
+}
+
+// IndexOpener opens index:<repo>=<path or url> catalog layers, backed by the
+// index of a Helm chart repository
+func IndexOpener(ctx context.Context, resolver *charts.Resolver) catalog.LayerOpener {
+	return func(arg string) (catalog.CatalogSource, error) {
+		repoName, location, ok := strings.Cut(arg, "=")
+		if !ok || repoName == "" || location == "" {
+			return nil, fmt.Errorf("expected index:<repo>=<path or url>")
+		}
+		return charts.NewIndexCatalog(ctx, resolver, repoName, location)
+	}
+}
@@ -0,0 +1,62 @@
+package signals
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ForceQuitExitCode is the exit code used when a second interrupt forces qtm to quit
+const ForceQuitExitCode = 130
+
+var (
+	mu        sync.Mutex
+	quitHooks []func()
+)
+
+// Handle cancels the returned context on the first SIGINT or SIGTERM so
+// running deployments can wind down. A second signal runs every hook
+// registered with OnForceQuit and exits immediately.
+func Handle(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		fmt.Println("\nReceived an interrupt, cancelling deployments... (interrupt again to force quit)")
+		cancel()
+
+		<-sigChan
+		fmt.Println("\nReceived a second interrupt, forcing quit")
+		forceQuit()
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigChan)
+		cancel()
+	}
+}
+
+// OnForceQuit registers a function to run before qtm is forced to quit. Hooks
+// run in reverse order of registration.
+func OnForceQuit(fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	quitHooks = append(quitHooks, fn)
+}
+
+func forceQuit() {
+	mu.Lock()
+	hooks := append([]func(){}, quitHooks...)
+	mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	os.Exit(ForceQuitExitCode)
+}
@@ -2,11 +2,8 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"os"
-	"os/signal"
 	"qtm/cmd"
-	"syscall"
+	"qtm/internal/signals"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
@@ -20,20 +17,10 @@ func main() {
 	}
 	defer logger.Sync()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	// The first interrupt cancels deployments, a second one forces a quit
+	ctx, cancel := signals.Handle(context.Background())
 	defer cancel()
 
-	// Set up a channel to listen for interrupt signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start a goroutine to listen for interrupt signals
-	go func() {
-		<-sigChan
-		fmt.Println("\nReceived an interrupt, cancelling deployments...")
-		cancel()
-	}()
-
 	// Initialize etcd client
 	etcdClient, err := clientv3.New(clientv3.Config{
 		Endpoints: []string{"localhost:2379"}, // Replace with your etcd endpoints
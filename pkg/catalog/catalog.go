@@ -1,10 +1,42 @@
 package catalog
 
+import (
+	"errors"
+	"fmt"
+	"qtm/pkg/values"
+)
+
+// ErrNotFound is returned when a catalog has no entry for an app
+var ErrNotFound = errors.New("no catalog entry found")
+
+// NotFoundError is returned when a catalog has no entry for an app in a
+// group. It matches ErrNotFound with errors.Is.
+type NotFoundError struct {
+	App     string
+	Group   string
+	Catalog string // File or prefix the app was looked up in
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%v for %s in %s", ErrNotFound, qualifiedName(e.Group, e.App), e.Catalog)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // Catalog represents a collection of app catalog entries.
 type CatalogItem struct {
-	Name      string `yaml:"name"`
-	Version   string `yaml:"version"`
-	HelmChart string `yaml:"helmChart"`
+	Name          string           `yaml:"name"`
+	Group         string           `yaml:"group,omitempty"`
+	Version       string           `yaml:"version"`
+	HelmChart     string           `yaml:"helmChart"`              // Local path, repo/chart or oci:// reference
+	ChartVersion  string           `yaml:"chartVersion,omitempty"` // Chart version or constraint for repo and oci charts, latest when empty
+	ChartDigest   string           `yaml:"chartDigest,omitempty"`  // Approved SHA-256 digest of the chart archive
+	Provenance    string           `yaml:"provenance,omitempty"`   // Path or URL of the chart's provenance file
+	values.Source `yaml:",inline"` // Catalog level Helm values
+
+	Origin string `yaml:"-"` // Layer of a layered catalog the item was read from
 }
 
 type Catalog struct {
@@ -12,8 +44,23 @@ type Catalog struct {
 }
 
 // CatalogSource defines an interface for types that can read catalog data.
+// The version may be empty for the current entry, or pin an exact version
+// or semver constraint.
 type CatalogSource interface {
-	FetchData(appName, appGroup string) (*CatalogItem, error)
+	FetchData(appName, appGroup, version string) (*CatalogItem, error)
+}
+
+// CatalogWriter defines an interface for catalogs that can be modified.
+// Items are validated with ValidateItem before they are written.
+type CatalogWriter interface {
+	PutData(item CatalogItem) error
+	DeleteData(appName, appGroup string) error
+}
+
+// CatalogLister defines an interface for catalogs that can list their items.
+// An empty group lists the items of every group.
+type CatalogLister interface {
+	ListData(appGroup string) ([]CatalogItem, error)
 }
 
 type CatalogSourceHolder struct {
@@ -1,16 +1,36 @@
 package catalog
 
 import (
-	"errors"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
-// FileCatalogSource reads catalog data from a file.
+// File layouts understood by FileCatalog
+const (
+	layoutList   = iota // A list of items, each naming its group
+	layoutGroups        // A mapping of group names to lists of items
+	layoutKeys          // A mapping of group/name keys to items
+)
+
+// FileCatalogSource reads catalog data from a file. Entries are keyed by group
+// and name, and may be written as a list, nested by group:
+//
+//	core:
+//	  - name: api
+//	    version: 1.2.0
+//
+// or keyed by group/name:
+//
+//	core/api:
+//	  version: 1.2.0
 type FileCatalog struct {
 	Filename string
-	items    map[string]CatalogItem
+	items    map[string]CatalogItem // Keyed by group/name, or name for items without a group
+	layout   int
 }
 
 func NewFileCatalogSource(filePath string) (*FileCatalog, error) {
@@ -19,27 +39,207 @@ func NewFileCatalogSource(filePath string) (*FileCatalog, error) {
 		return nil, err
 	}
 
-	var items []CatalogItem
-	if err := yaml.Unmarshal(data, &items); err != nil {
-		return nil, err
+	fc := &FileCatalog{
+		Filename: filePath,
+		items:    make(map[string]CatalogItem),
+	}
+	if err := fc.load(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
 	}
+	return fc, nil
+}
 
-	catalog := &Catalog{
-		items: make(map[string]CatalogItem),
+// load parses any of the file layouts, refusing duplicate entries
+func (fc *FileCatalog) load(data []byte) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
 	}
 
-	for _, item := range items {
-		catalog.items[item.Name] = item
+	if _, ok := doc.(map[interface{}]interface{}); !ok {
+		var items []CatalogItem
+		if err := yaml.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := fc.add(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var entries yaml.MapSlice
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		key, ok := entry.Key.(string)
+		if !ok {
+			return fmt.Errorf("catalog key %v is not a string", entry.Key)
+		}
+		raw, err := yaml.Marshal(entry.Value)
+		if err != nil {
+			return err
+		}
+
+		layout := layoutKeys
+		if _, isList := entry.Value.([]interface{}); isList {
+			layout = layoutGroups
+		}
+		if i > 0 && layout != fc.layout {
+			return fmt.Errorf("catalog mixes group lists and group/name keys at %s", key)
+		}
+		fc.layout = layout
+
+		if layout == layoutGroups {
+			var items []CatalogItem
+			if err := yaml.Unmarshal(raw, &items); err != nil {
+				return fmt.Errorf("group %s: %w", key, err)
+			}
+			for _, item := range items {
+				if item.Group != "" && item.Group != key {
+					return fmt.Errorf("%s is listed under group %s but sets group %s", item.Name, key, item.Group)
+				}
+				item.Group = key
+				if err := fc.add(item); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		group, name, ok := strings.Cut(key, "/")
+		if !ok || group == "" || name == "" {
+			return fmt.Errorf("catalog key %q must be group/name", key)
+		}
+		var item CatalogItem
+		if err := yaml.Unmarshal(raw, &item); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if (item.Name != "" && item.Name != name) || (item.Group != "" && item.Group != group) {
+			return fmt.Errorf("entry %s names %s", key, qualifiedName(item.Group, item.Name))
+		}
+		item.Group, item.Name = group, name
+		if err := fc.add(item); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return &FileCatalog{
-		items: catalog.items,
-	}, nil
+// add stores a loaded item, refusing a second entry for the same app
+func (fc *FileCatalog) add(item CatalogItem) error {
+	if item.Name == "" {
+		return fmt.Errorf("catalog entry without a name in group %q", item.Group)
+	}
+	key := qualifiedName(item.Group, item.Name)
+	if _, exists := fc.items[key]; exists {
+		return fmt.Errorf("duplicate catalog entry for %s", key)
+	}
+	fc.items[key] = item
+	return nil
 }
 
-func (fc *FileCatalog) FetchData(appName, appGroup string) (*CatalogItem, error) {
+// lookup finds the entry of an app. Entries without a group match any group,
+// and an app looked up without a group must have a single entry.
+func (fc *FileCatalog) lookup(appName, appGroup string) (string, CatalogItem, error) {
+	if item, ok := fc.items[qualifiedName(appGroup, appName)]; ok {
+		return qualifiedName(appGroup, appName), item, nil
+	}
 	if item, ok := fc.items[appName]; ok {
-		return &item, nil
+		return appName, item, nil
+	}
+
+	if appGroup == "" {
+		var keys []string
+		for key, item := range fc.items {
+			if item.Name == appName {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 1 {
+			return keys[0], fc.items[keys[0]], nil
+		}
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			return "", CatalogItem{}, fmt.Errorf("%s is ambiguous in %s, it exists as %s", appName, fc.Filename, strings.Join(keys, ", "))
+		}
+	}
+	return "", CatalogItem{}, &NotFoundError{App: appName, Group: appGroup, Catalog: fc.Filename}
+}
+
+// FetchData returns the catalog item of an app. A catalog file holds a single
+// version of each app, so a pinned version must match it.
+func (fc *FileCatalog) FetchData(appName, appGroup, version string) (*CatalogItem, error) {
+	_, item, err := fc.lookup(appName, appGroup)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveVersion(appName, appGroup, version, []CatalogItem{item})
+}
+
+// PutData adds or replaces a catalog item and saves the file
+func (fc *FileCatalog) PutData(item CatalogItem) error {
+	if err := ValidateItem(item); err != nil {
+		return err
+	}
+
+	fc.items[qualifiedName(item.Group, item.Name)] = item
+	return fc.save()
+}
+
+// DeleteData removes a catalog item and saves the file
+func (fc *FileCatalog) DeleteData(appName, appGroup string) error {
+	key, _, err := fc.lookup(appName, appGroup)
+	if err != nil {
+		return err
+	}
+
+	delete(fc.items, key)
+	return fc.save()
+}
+
+// ListData returns the catalog items of a group, or every item when the
+// group is empty, sorted by group and name
+func (fc *FileCatalog) ListData(appGroup string) ([]CatalogItem, error) {
+	var items []CatalogItem
+	for _, item := range fc.items {
+		if appGroup == "" || item.Group == appGroup {
+			items = append(items, item)
+		}
+	}
+	sortItems(items)
+	return items, nil
+}
+
+// save writes every item back to the catalog file in the layout it was read in
+func (fc *FileCatalog) save() error {
+	items, _ := fc.ListData("")
+
+	var doc interface{} = items
+	switch fc.layout {
+	case layoutGroups:
+		var groups yaml.MapSlice
+		for _, item := range items {
+			if len(groups) == 0 || groups[len(groups)-1].Key != item.Group {
+				groups = append(groups, yaml.MapItem{Key: item.Group, Value: []CatalogItem{}})
+			}
+			last := &groups[len(groups)-1]
+			last.Value = append(last.Value.([]CatalogItem), item)
+		}
+		doc = groups
+	case layoutKeys:
+		var keyed yaml.MapSlice
+		for _, item := range items {
+			keyed = append(keyed, yaml.MapItem{Key: qualifiedName(item.Group, item.Name), Value: item})
+		}
+		doc = keyed
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
 	}
-	return nil, errors.New("app not found")
+	return os.WriteFile(fc.Filename, data, 0o644)
 }
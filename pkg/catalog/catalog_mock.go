@@ -62,8 +62,12 @@ func NewMockCatalogSource(opts ...MockCatalogOption) *MockCatalog {
 	return mc
 }
 
-func (mc *MockCatalog) FetchData(appName, appGroup string) (*CatalogItem, error) {
-	return mc.lookupFunc(appName)
+func (mc *MockCatalog) FetchData(appName, appGroup, version string) (*CatalogItem, error) {
+	item, err := mc.lookupFunc(appName)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveVersion(appName, appGroup, version, []CatalogItem{*item})
 }
 
 func (mc *MockCatalog) normalLookup(appName string) (*CatalogItem, error) {
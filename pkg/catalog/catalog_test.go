@@ -0,0 +1,291 @@
+package catalog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateItem(t *testing.T) {
+	valid := CatalogItem{Name: "web", Group: "payments", Version: "1.2.3", HelmChart: "stable/web"}
+
+	tests := []struct {
+		name    string
+		modify  func(*CatalogItem)
+		wantErr bool
+	}{
+		{name: "Valid", modify: func(i *CatalogItem) {}},
+		{name: "Leading v", modify: func(i *CatalogItem) { i.Version = "v1.2.3" }},
+		{name: "Local chart", modify: func(i *CatalogItem) { i.HelmChart = "./charts/web" }},
+		{name: "OCI chart", modify: func(i *CatalogItem) { i.HelmChart = "oci://registry.example.com/charts/web" }},
+		{name: "Missing group", modify: func(i *CatalogItem) { i.Group = "" }, wantErr: true},
+		{name: "Reserved group", modify: func(i *CatalogItem) { i.Group = "env" }, wantErr: true},
+		{name: "Reserved history group", modify: func(i *CatalogItem) { i.Group = "catalog-history" }, wantErr: true},
+		{name: "Bad version", modify: func(i *CatalogItem) { i.Version = "latest" }, wantErr: true},
+		{name: "Partial version", modify: func(i *CatalogItem) { i.Version = "1.2" }, wantErr: true},
+		{name: "Bad chart", modify: func(i *CatalogItem) { i.HelmChart = "https://example.com/web.tgz" }, wantErr: true},
+		{name: "Bare chart name", modify: func(i *CatalogItem) { i.HelmChart = "web" }, wantErr: true},
+		{name: "Bad digest", modify: func(i *CatalogItem) { i.ChartDigest = "sha256:abc" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := valid
+			tt.modify(&item)
+			if err := ValidateItem(item); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateItem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileCatalogWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	if err := os.WriteFile(path, []byte("- name: app1\n  group: test\n  version: 1.1.1\n  helmChart: app1-1.0.0.tgz\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fc, err := NewFileCatalogSource(path)
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	if err := fc.PutData(CatalogItem{Name: "app2", Group: "test", Version: "2.0.0", HelmChart: "stable/app2"}); err != nil {
+		t.Fatalf("PutData failed: %v", err)
+	}
+	if err := fc.PutData(CatalogItem{Name: "app3", Group: "test", Version: "not-a-version", HelmChart: "stable/app3"}); err == nil {
+		t.Errorf("Expected an invalid version to be refused")
+	}
+	if err := fc.DeleteData("app1", "test"); err != nil {
+		t.Fatalf("DeleteData failed: %v", err)
+	}
+
+	// Changes must survive reloading the file
+	reloaded, err := NewFileCatalogSource(path)
+	if err != nil {
+		t.Fatalf("Failed to reload catalog: %v", err)
+	}
+	items, err := reloaded.ListData("test")
+	if err != nil {
+		t.Fatalf("ListData failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "app2" || items[0].Version != "2.0.0" {
+		t.Errorf("Unexpected catalog items %+v", items)
+	}
+}
+
+// countingCatalog counts the lookups made on a file catalog
+type countingCatalog struct {
+	*FileCatalog
+	fetches, lists int
+}
+
+func (cc *countingCatalog) FetchData(appName, appGroup, version string) (*CatalogItem, error) {
+	cc.fetches++
+	return cc.FileCatalog.FetchData(appName, appGroup, version)
+}
+
+func (cc *countingCatalog) ListData(appGroup string) ([]CatalogItem, error) {
+	cc.lists++
+	return cc.FileCatalog.ListData(appGroup)
+}
+
+// TestPrefetch tests that listable catalogs are read in one batch, with
+// pinned versions looked up on their own
+func TestPrefetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	if err := os.WriteFile(path, []byte("- {name: app1, group: test, version: 1.1.1, helmChart: stable/app1}\n- {name: app2, group: test, version: 2.0.0, helmChart: stable/app2}\n- {name: app3, group: ops, version: 3.0.0, helmChart: stable/app3}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fc, err := NewFileCatalogSource(path)
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	source := &countingCatalog{FileCatalog: fc}
+
+	refs := []Ref{{Name: "app1", Group: "test"}, {Name: "app2", Group: "test"}, {Name: "app3", Group: "ops", Version: "^3"}}
+	snapshot, err := Prefetch(source, refs)
+	if err != nil {
+		t.Fatalf("Prefetch failed: %v", err)
+	}
+	if source.lists != 1 || source.fetches != 1 {
+		t.Errorf("Prefetch made %d listings and %d lookups, want 1 and 1", source.lists, source.fetches)
+	}
+	if len(snapshot.Items()) != 3 {
+		t.Errorf("Snapshot items = %+v, want 3", snapshot.Items())
+	}
+
+	_, err = Prefetch(source, []Ref{{Name: "app1", Group: "test"}, {Name: "missing", Group: "ops"}})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Prefetch error = %v, want a not found error", err)
+	}
+}
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		pin     string
+		want    bool
+	}{
+		{version: "1.2.3", pin: "", want: true},
+		{version: "1.2.3", pin: "1.2.3", want: true},
+		{version: "v1.2.3", pin: "1.2.3", want: true},
+		{version: "1.2.3", pin: "1.2.4", want: false},
+		{version: "1.2.3", pin: "~1.2", want: true},
+		{version: "1.3.0", pin: ">=1.0.0, <1.3.0", want: false},
+		{version: "not-semver", pin: "^1", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesVersion(tt.version, tt.pin); got != tt.want {
+			t.Errorf("MatchesVersion(%q, %q) = %v, want %v", tt.version, tt.pin, got, tt.want)
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	candidates := []CatalogItem{
+		{Name: "app", Version: "1.4.0", HelmChart: "old"},
+		{Name: "app", Version: "2.3.1"},
+		{Name: "app", Version: "1.9.2"},
+		{Name: "app", Version: "2.4.0"},
+		{Name: "app", Version: "1.4.0", HelmChart: "new"},
+	}
+
+	tests := []struct {
+		pin   string
+		want  string
+		chart string
+	}{
+		{pin: "^2.3", want: "2.4.0"},
+		{pin: ">=1.4 <2.0", want: "1.9.2"},
+		{pin: "1.4.0", want: "1.4.0", chart: "new"},
+	}
+	for _, tt := range tests {
+		item, err := ResolveVersion("app", "core", tt.pin, candidates)
+		if err != nil {
+			t.Fatalf("ResolveVersion(%q) failed: %v", tt.pin, err)
+		}
+		if item.Version != tt.want || (tt.chart != "" && item.HelmChart != tt.chart) {
+			t.Errorf("ResolveVersion(%q) = %s %s, want %s %s", tt.pin, item.Version, item.HelmChart, tt.want, tt.chart)
+		}
+	}
+
+	_, err := ResolveVersion("app", "core", "^3", candidates)
+	var notFound *VersionNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected a VersionNotFoundError, got %v", err)
+	}
+	if want := `no version of core/app satisfies "^3" (available: 2.4.0, 2.3.1, 1.9.2, 1.4.0)`; err.Error() != want {
+		t.Errorf("Error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestLayeredSource(t *testing.T) {
+	dir := t.TempDir()
+	overrides := filepath.Join(dir, "overrides.yaml")
+	shared := filepath.Join(dir, "shared.yaml")
+	if err := os.WriteFile(overrides, []byte("- {name: api, group: core, version: 2.0.0-dev, helmChart: ./charts/api}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(shared, []byte("- {name: api, group: core, version: 1.4.0, helmChart: charts/api}\n- {name: web, group: core, version: 1.0.0, helmChart: charts/web}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ls, err := OpenLayers([]string{"file:" + overrides, shared}, nil, "qtm", nil)
+	if err != nil {
+		t.Fatalf("OpenLayers failed: %v", err)
+	}
+
+	tests := []struct {
+		app, version, want, origin string
+	}{
+		{app: "api", want: "2.0.0-dev", origin: "file:" + overrides},
+		{app: "web", want: "1.0.0", origin: "file:" + shared},
+		{app: "api", version: "^1", want: "1.4.0", origin: "file:" + shared},
+	}
+	for _, tt := range tests {
+		item, err := ls.FetchData(tt.app, "core", tt.version)
+		if err != nil {
+			t.Fatalf("FetchData(%s, %q) failed: %v", tt.app, tt.version, err)
+		}
+		if item.Version != tt.want || item.Origin != tt.origin {
+			t.Errorf("FetchData(%s, %q) = %s from %s, want %s from %s", tt.app, tt.version, item.Version, item.Origin, tt.want, tt.origin)
+		}
+	}
+
+	if _, err := ls.FetchData("db", "core", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing app, got %v", err)
+	}
+	if _, err := OpenLayers([]string{"s3://bucket"}, nil, "qtm", nil); err == nil {
+		t.Errorf("Expected an error for an unknown catalog spec")
+	}
+}
+
+func TestFileCatalogGroups(t *testing.T) {
+	layouts := map[string]string{
+		"nested": "core:\n  - {name: api, version: 1.0.0, helmChart: charts/core-api}\nedge:\n  - {name: api, version: 2.0.0, helmChart: charts/edge-api}\n",
+		"keyed":  "core/api: {version: 1.0.0, helmChart: charts/core-api}\nedge/api: {version: 2.0.0, helmChart: charts/edge-api}\n",
+		"list":   "- {name: api, group: core, version: 1.0.0, helmChart: charts/core-api}\n- {name: api, group: edge, version: 2.0.0, helmChart: charts/edge-api}\n",
+	}
+
+	for name, content := range layouts {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "catalog.yaml")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			fc, err := NewFileCatalogSource(path)
+			if err != nil {
+				t.Fatalf("Failed to load catalog: %v", err)
+			}
+
+			for group, want := range map[string]string{"core": "1.0.0", "edge": "2.0.0"} {
+				item, err := fc.FetchData("api", group, "")
+				if err != nil || item.Version != want || item.Group != group {
+					t.Errorf("FetchData(api, %s) = %+v, %v, want version %s", group, item, err, want)
+				}
+			}
+
+			_, err = fc.FetchData("api", "payments", "")
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) || notFound.Group != "payments" || !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected a NotFoundError for group payments, got %v", err)
+			}
+			if _, err := fc.FetchData("api", "", ""); err == nil {
+				t.Errorf("Expected an error for an ambiguous app")
+			}
+
+			// Saving keeps the layout the file was written in
+			if err := fc.PutData(CatalogItem{Name: "web", Group: "core", Version: "1.1.0", HelmChart: "charts/web"}); err != nil {
+				t.Fatalf("PutData failed: %v", err)
+			}
+			reloaded, err := NewFileCatalogSource(path)
+			if err != nil {
+				t.Fatalf("Failed to reload catalog: %v", err)
+			}
+			if reloaded.layout != fc.layout {
+				t.Errorf("Layout changed from %d to %d", fc.layout, reloaded.layout)
+			}
+			if items, _ := reloaded.ListData(""); len(items) != 3 {
+				t.Errorf("Reloaded %d items, want 3", len(items))
+			}
+		})
+	}
+}
+
+func TestFileCatalogDuplicates(t *testing.T) {
+	for _, content := range []string{
+		"- {name: api, group: core, version: 1.0.0, helmChart: a/b}\n- {name: api, group: core, version: 2.0.0, helmChart: a/b}\n",
+		"core:\n  - {name: api, version: 1.0.0, helmChart: a/b}\n  - {name: api, version: 2.0.0, helmChart: a/b}\n",
+		"core/api: {version: 1.0.0, helmChart: a/b}\ncore/api: {version: 2.0.0, helmChart: a/b}\n",
+	} {
+		path := filepath.Join(t.TempDir(), "catalog.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewFileCatalogSource(path); err == nil {
+			t.Errorf("Expected a duplicate entry error loading %q", content)
+		}
+	}
+}
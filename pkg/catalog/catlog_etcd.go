@@ -3,11 +3,29 @@ package catalog
 import (
 	"context"
 	"fmt"
-	"path/filepath"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v2"
 )
 
+// Keys stored under <prefix>/<group>/<app>/ for every catalog item
+const (
+	keyVersion      = "version"
+	keyChart        = "chart"
+	keyChartVersion = "chartVersion"
+	keyChartDigest  = "chartDigest"
+	keyProvenance   = "provenance"
+	keyValues       = "values"
+)
+
+// historyDir holds the history of every catalog item, one key per change
+// under <prefix>/catalog-history/<group>/<app>/<timestamp>
+const historyDir = "catalog-history"
+
 type RemoteEtcdSource struct {
 	Client *clientv3.Client
 	Prefix string
@@ -20,45 +38,239 @@ func NewRemoteCatalogSource(client *clientv3.Client, prefix string) *RemoteEtcdS
 	}
 }
 
-func (res *RemoteEtcdSource) FetchData(appName, appGroup string) (*CatalogItem, error) {
-	// Connect to etcd
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints: []string{"localhost:2379"}, // Replace with your etcd endpoints
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to etcd: %v", err)
+// FetchData returns the current catalog item, or when a version is pinned the
+// item with the highest version satisfying it among the current item and
+// every version recorded in the history of the app
+func (res *RemoteEtcdSource) FetchData(appName, appGroup, version string) (*CatalogItem, error) {
+	item, err := res.fetchCurrent(appName, appGroup)
+	if version == "" {
+		return item, err
 	}
-	defer cli.Close()
 
+	history, herr := res.History(appName, appGroup)
+	if herr != nil {
+		return nil, herr
+	}
+	var candidates []CatalogItem
+	for _, entry := range history {
+		if entry.Action == HistorySet {
+			candidates = append(candidates, entry.Item)
+		}
+	}
+	if err == nil {
+		candidates = append(candidates, *item)
+	}
+	return ResolveVersion(appName, appGroup, version, candidates)
+}
+
+func (res *RemoteEtcdSource) fetchCurrent(appName, appGroup string) (*CatalogItem, error) {
 	// Build the key
-	key := filepath.Join(appGroup, appName)
+	key := res.itemKey(appName, appGroup)
+
+	resp, err := res.Client.Get(context.Background(), key+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %v", key, err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, &NotFoundError{App: appName, Group: appGroup, Catalog: res.Prefix}
+	}
+
+	fields := make(map[string]string)
+	for _, kv := range resp.Kvs {
+		fields[strings.TrimPrefix(string(kv.Key), key+"/")] = string(kv.Value)
+	}
 
-	// Query /version
-	respVersion, err := cli.Get(context.Background(), filepath.Join(key, "version"))
+	item, err := itemFromFields(appName, appGroup, fields)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query /version: %v", err)
+		return nil, fmt.Errorf("%w for %s", err, key)
 	}
-	if len(respVersion.Kvs) == 0 {
-		return nil, fmt.Errorf("no version found for %s", key)
+	return item, nil
+}
+
+// PutData writes a catalog item, removing any optional keys it does not set
+func (res *RemoteEtcdSource) PutData(item CatalogItem) error {
+	if err := ValidateItem(item); err != nil {
+		return err
+	}
+
+	key := res.itemKey(item.Name, item.Group)
+	fields := map[string]string{
+		keyVersion:      item.Version,
+		keyChart:        item.HelmChart,
+		keyChartVersion: item.ChartVersion,
+		keyChartDigest:  item.ChartDigest,
+		keyProvenance:   item.Provenance,
+	}
+	if len(item.Values) > 0 {
+		data, err := yaml.Marshal(item.Values)
+		if err != nil {
+			return fmt.Errorf("failed to encode values for %s: %v", key, err)
+		}
+		fields[keyValues] = string(data)
+	} else {
+		fields[keyValues] = ""
 	}
-	version := string(respVersion.Kvs[0].Value)
 
-	// Query /chart
-	respChart, err := cli.Get(context.Background(), filepath.Join(key, "chart"))
+	var ops []clientv3.Op
+	for field, value := range fields {
+		if value == "" {
+			ops = append(ops, clientv3.OpDelete(path.Join(key, field)))
+			continue
+		}
+		ops = append(ops, clientv3.OpPut(path.Join(key, field), value))
+	}
+
+	historyOp, err := res.historyOp(HistorySet, item)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query /chart: %v", err)
+		return err
 	}
-	if len(respChart.Kvs) == 0 {
-		return nil, fmt.Errorf("no chart found for %s", key)
+	ops = append(ops, historyOp)
+
+	_, err = res.Client.Txn(context.Background()).Then(ops...).Commit()
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", key, err)
 	}
-	chart := string(respChart.Kvs[0].Value)
+	return nil
+}
 
-	// Build the catalog item structure
-	catalogItem := &CatalogItem{
-		Name:      appName,
-		Version:   version,
-		HelmChart: chart,
+// DeleteData removes a catalog item
+func (res *RemoteEtcdSource) DeleteData(appName, appGroup string) error {
+	key := res.itemKey(appName, appGroup)
+	item, err := res.fetchCurrent(appName, appGroup)
+	if err != nil {
+		item = &CatalogItem{Name: appName, Group: appGroup}
+	}
+	historyOp, err := res.historyOp(HistoryDelete, *item)
+	if err != nil {
+		return err
 	}
 
-	return catalogItem, nil
+	resp, err := res.Client.Txn(context.Background()).
+		Then(clientv3.OpDelete(key+"/", clientv3.WithPrefix()), historyOp).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %v", key, err)
+	}
+	if resp.Responses[0].GetResponseDeleteRange().Deleted == 0 {
+		return fmt.Errorf("no catalog entry found for %s", key)
+	}
+	return nil
+}
+
+// History returns every recorded change to a catalog item, oldest first
+func (res *RemoteEtcdSource) History(appName, appGroup string) ([]HistoryEntry, error) {
+	prefix := res.historyKey(appName, appGroup) + "/"
+	resp, err := res.Client.Get(context.Background(), prefix, clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %v", prefix, err)
+	}
+
+	var entries []HistoryEntry
+	for _, kv := range resp.Kvs {
+		var entry HistoryEntry
+		if err := yaml.Unmarshal(kv.Value, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history entry %s: %v", kv.Key, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// historyOp records a change to a catalog item, keyed by time so that keys
+// sort in the order the changes were made
+func (res *RemoteEtcdSource) historyOp(action string, item CatalogItem) (clientv3.Op, error) {
+	entry := HistoryEntry{Time: time.Now().UTC(), Action: action, Item: item}
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return clientv3.Op{}, fmt.Errorf("failed to encode history for %s: %v", res.itemKey(item.Name, item.Group), err)
+	}
+	key := path.Join(res.historyKey(item.Name, item.Group), fmt.Sprintf("%020d", entry.Time.UnixNano()))
+	return clientv3.OpPut(key, string(data)), nil
+}
+
+// ListData returns the catalog items of a group, or of every group when the
+// group is empty, sorted by group and name
+func (res *RemoteEtcdSource) ListData(appGroup string) ([]CatalogItem, error) {
+	prefix := res.Prefix + "/"
+	if appGroup != "" {
+		prefix = path.Join(res.Prefix, appGroup) + "/"
+	}
+
+	resp, err := res.Client.Get(context.Background(), prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %v", prefix, err)
+	}
+
+	// Catalog keys are exactly <prefix>/<group>/<app>/<field>, anything
+	// else under the prefix such as sessions and suites is skipped
+	type itemID struct{ group, name string }
+	grouped := make(map[itemID]map[string]string)
+	for _, kv := range resp.Kvs {
+		parts := strings.Split(strings.TrimPrefix(string(kv.Key), res.Prefix+"/"), "/")
+		if len(parts) != 3 {
+			continue
+		}
+		id := itemID{group: parts[0], name: parts[1]}
+		if grouped[id] == nil {
+			grouped[id] = make(map[string]string)
+		}
+		grouped[id][parts[2]] = string(kv.Value)
+	}
+
+	var items []CatalogItem
+	for id, fields := range grouped {
+		item, err := itemFromFields(id.name, id.group, fields)
+		if err != nil {
+			continue
+		}
+		items = append(items, *item)
+	}
+	sortItems(items)
+	return items, nil
+}
+
+func (res *RemoteEtcdSource) itemKey(appName, appGroup string) string {
+	return path.Join(res.Prefix, appGroup, appName)
+}
+
+func (res *RemoteEtcdSource) historyKey(appName, appGroup string) string {
+	return path.Join(res.Prefix, historyDir, appGroup, appName)
+}
+
+// itemFromFields builds a catalog item from the keys stored for it
+func itemFromFields(appName, appGroup string, fields map[string]string) (*CatalogItem, error) {
+	if fields[keyVersion] == "" {
+		return nil, fmt.Errorf("no version found")
+	}
+	if fields[keyChart] == "" {
+		return nil, fmt.Errorf("no chart found")
+	}
+
+	item := &CatalogItem{
+		Name:         appName,
+		Group:        appGroup,
+		Version:      fields[keyVersion],
+		HelmChart:    fields[keyChart],
+		ChartVersion: fields[keyChartVersion],
+		ChartDigest:  fields[keyChartDigest],
+		Provenance:   fields[keyProvenance],
+	}
+	if data, ok := fields[keyValues]; ok {
+		if err := yaml.Unmarshal([]byte(data), &item.Values); err != nil {
+			return nil, fmt.Errorf("failed to parse values: %v", err)
+		}
+	}
+	return item, nil
+}
+
+// sortItems orders catalog items by group and name
+func sortItems(items []CatalogItem) {
+	sort.Slice(items, func(a, b int) bool {
+		if items[a].Group != items[b].Group {
+			return items[a].Group < items[b].Group
+		}
+		return items[a].Name < items[b].Name
+	})
 }
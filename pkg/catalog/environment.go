@@ -0,0 +1,27 @@
+package catalog
+
+import (
+	"fmt"
+	"path"
+)
+
+// environmentDir holds the catalog of every environment, each under
+// <prefix>/env/<environment>/<group>/<app>
+const environmentDir = "env"
+
+// EnvironmentPrefix returns the etcd prefix of the catalog of an environment,
+// or the root prefix itself when the environment is empty
+func EnvironmentPrefix(root, env string) string {
+	if env == "" {
+		return root
+	}
+	return path.Join(root, environmentDir, env)
+}
+
+// ValidateEnvironment checks an environment name before it is used in a key
+func ValidateEnvironment(env string) error {
+	if !nameRe.MatchString(env) {
+		return fmt.Errorf("invalid environment %q", env)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package catalog
+
+import (
+	"time"
+)
+
+// History actions
+const (
+	HistorySet    = "set"
+	HistoryDelete = "delete"
+)
+
+// HistoryEntry records a single change to the catalog entry of an app
+type HistoryEntry struct {
+	Time   time.Time   `yaml:"time"`
+	Action string      `yaml:"action"`
+	Item   CatalogItem `yaml:"item"`
+}
+
+// CatalogHistory defines an interface for catalogs that keep every change
+// made to their entries. Entries are returned oldest first.
+type CatalogHistory interface {
+	History(appName, appGroup string) ([]HistoryEntry, error)
+}
@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Layer is a named catalog in a LayeredSource
+type Layer struct {
+	Name   string
+	Source CatalogSource
+}
+
+// LayeredSource chains catalogs, for example local file overrides over an
+// environment catalog over the global catalog. Each app is read from the
+// first layer that has an entry for it, and the item's Origin names that
+// layer.
+type LayeredSource struct {
+	Layers []Layer
+}
+
+func NewLayeredSource(layers ...Layer) *LayeredSource {
+	return &LayeredSource{Layers: layers}
+}
+
+// FetchData returns the item from the first layer that has a matching entry.
+// Layers without the app, or without a version satisfying the pin, are
+// skipped, any other error stops the lookup.
+func (ls *LayeredSource) FetchData(appName, appGroup, version string) (*CatalogItem, error) {
+	var names []string
+	for _, layer := range ls.Layers {
+		item, err := layer.Source.FetchData(appName, appGroup, version)
+		if err == nil {
+			item.Origin = layer.Name
+			return item, nil
+		}
+
+		var versionErr *VersionNotFoundError
+		if !errors.Is(err, ErrNotFound) && !errors.As(err, &versionErr) {
+			return nil, fmt.Errorf("catalog %s: %w", layer.Name, err)
+		}
+		names = append(names, layer.Name)
+	}
+	return nil, &NotFoundError{App: appName, Group: appGroup, Catalog: strings.Join(names, ", ")}
+}
+
+// LayerOpener opens a catalog from the part of a spec after its prefix, for
+// catalog types implemented outside this package
+type LayerOpener func(arg string) (CatalogSource, error)
+
+// OpenLayers builds a LayeredSource from catalog specs, highest precedence
+// first. A spec is one of:
+//
+//	file:<path>  a local catalog file, any spec ending in .yaml or .yml is also read as a file
+//	env:<name>   the etcd catalog of an environment
+//	global       the global etcd catalog
+//
+// or <prefix>:<arg> for any prefix in openers.
+func OpenLayers(specs []string, client *clientv3.Client, root string, openers map[string]LayerOpener) (*LayeredSource, error) {
+	ls := NewLayeredSource()
+	for _, spec := range specs {
+		layer, err := openLayer(spec, client, root, openers)
+		if err != nil {
+			return nil, err
+		}
+		ls.Layers = append(ls.Layers, layer)
+	}
+	return ls, nil
+}
+
+func openLayer(spec string, client *clientv3.Client, root string, openers map[string]LayerOpener) (Layer, error) {
+	if prefix, arg, ok := strings.Cut(spec, ":"); ok && openers[prefix] != nil {
+		source, err := openers[prefix](arg)
+		if err != nil {
+			return Layer{}, fmt.Errorf("failed to open catalog %s: %w", spec, err)
+		}
+		return Layer{Name: spec, Source: source}, nil
+	}
+
+	switch {
+	case spec == "global":
+		return Layer{Name: spec, Source: NewRemoteCatalogSource(client, root)}, nil
+	case strings.HasPrefix(spec, "env:"):
+		env := strings.TrimPrefix(spec, "env:")
+		if err := ValidateEnvironment(env); err != nil {
+			return Layer{}, err
+		}
+		return Layer{Name: spec, Source: NewRemoteCatalogSource(client, EnvironmentPrefix(root, env))}, nil
+	case strings.HasPrefix(spec, "file:"), strings.HasSuffix(spec, ".yaml"), strings.HasSuffix(spec, ".yml"):
+		path := strings.TrimPrefix(spec, "file:")
+		fc, err := NewFileCatalogSource(path)
+		if err != nil {
+			return Layer{}, fmt.Errorf("failed to read catalog %s: %w", path, err)
+		}
+		return Layer{Name: "file:" + path, Source: fc}, nil
+	}
+	var prefixes []string
+	for prefix := range openers {
+		prefixes = append(prefixes, prefix+":<arg>")
+	}
+	sort.Strings(prefixes)
+	expected := strings.Join(append([]string{"file:<path>", "env:<name>", "global"}, prefixes...), ", ")
+	return Layer{}, fmt.Errorf("unknown catalog %q, expected one of %s", spec, expected)
+}
@@ -0,0 +1,114 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Ref identifies a catalog lookup: an app, its group and an optional version
+// or constraint
+type Ref struct {
+	Name    string
+	Group   string
+	Version string
+}
+
+// Snapshot is a CatalogSource holding entries fetched up front, so every
+// lookup during a rollout sees the catalog as it was when the rollout began
+type Snapshot struct {
+	Taken time.Time
+	items map[Ref]CatalogItem
+}
+
+// Prefetch looks up every ref before anything is deployed. Sources that can
+// list their items are read in one batch, and only refs the listing cannot
+// answer, such as pinned versions, are looked up one at a time. All failed
+// lookups are reported together.
+func Prefetch(source CatalogSource, refs []Ref) (*Snapshot, error) {
+	snapshot := &Snapshot{Taken: time.Now().UTC(), items: make(map[Ref]CatalogItem)}
+
+	listed, err := listCurrent(source, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, ref := range refs {
+		if _, done := snapshot.items[ref]; done {
+			continue
+		}
+		if item, ok := listed[ref]; ok {
+			snapshot.items[ref] = item
+			continue
+		}
+		item, err := source.FetchData(ref.Name, ref.Group, ref.Version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", qualifiedName(ref.Group, ref.Name), err))
+			continue
+		}
+		snapshot.items[ref] = *item
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%d of %d catalog entries could not be read:\n%w", len(errs), len(refs), errors.Join(errs...))
+	}
+	return snapshot, nil
+}
+
+// listCurrent reads the current items of the groups named by unpinned refs
+// with a single listing. It returns nothing when the source cannot list its
+// items or every ref needs its own lookup.
+func listCurrent(source CatalogSource, refs []Ref) (map[Ref]CatalogItem, error) {
+	lister, ok := source.(CatalogLister)
+	if !ok {
+		return nil, nil
+	}
+
+	groups := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Group != "" && ref.Version == "" {
+			groups[ref.Group] = true
+		}
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	// One group is listed on its own, several need the whole catalog
+	var group string
+	if len(groups) == 1 {
+		for only := range groups {
+			group = only
+		}
+	}
+
+	items, err := lister.ListData(group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the catalog: %w", err)
+	}
+	listed := make(map[Ref]CatalogItem, len(items))
+	for _, item := range items {
+		listed[Ref{Name: item.Name, Group: item.Group}] = item
+	}
+	return listed, nil
+}
+
+// FetchData returns an entry from the snapshot. Only refs that were
+// prefetched can be looked up.
+func (s *Snapshot) FetchData(appName, appGroup, version string) (*CatalogItem, error) {
+	item, ok := s.items[Ref{Name: appName, Group: appGroup, Version: version}]
+	if !ok {
+		return nil, &NotFoundError{App: appName, Group: appGroup, Catalog: "the catalog snapshot"}
+	}
+	return &item, nil
+}
+
+// Items returns every entry in the snapshot, sorted by group and name
+func (s *Snapshot) Items() []CatalogItem {
+	items := make([]CatalogItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sortItems(items)
+	return items
+}
@@ -0,0 +1,82 @@
+package catalog
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+var (
+	// nameRe matches app, group and chart repository names
+	nameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9_.]*[a-z0-9])?$`)
+	// repoChartRe matches repo/chart references
+	repoChartRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9_.]*[a-z0-9])?/[a-z0-9]([-a-z0-9_.]*[a-z0-9])?$`)
+)
+
+// reservedGroups are the keys under the etcd prefix that hold sessions, suites,
+// environment catalogs, catalog history and promotion records. A catalog
+// group with one of these names would collide with them.
+var reservedGroups = map[string]bool{
+	"sessions":     true,
+	"suites":       true,
+	environmentDir: true,
+	historyDir:     true,
+	"promotions":   true,
+}
+
+// ValidateItem checks a catalog item before it is written
+func ValidateItem(item CatalogItem) error {
+	if !nameRe.MatchString(item.Name) {
+		return fmt.Errorf("invalid app name %q", item.Name)
+	}
+	if !nameRe.MatchString(item.Group) {
+		return fmt.Errorf("invalid group %q for %s", item.Group, item.Name)
+	}
+	if reservedGroups[item.Group] {
+		return fmt.Errorf("group %q of %s is reserved", item.Group, item.Name)
+	}
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(item.Version, "v")); err != nil {
+		return fmt.Errorf("invalid version %q for %s: %w", item.Version, item.Name, err)
+	}
+	if err := ValidateChartRef(item.HelmChart); err != nil {
+		return fmt.Errorf("invalid chart for %s: %w", item.Name, err)
+	}
+	if item.ChartVersion != "" {
+		if _, err := semver.NewConstraint(item.ChartVersion); err != nil {
+			return fmt.Errorf("invalid chart version %q for %s: %w", item.ChartVersion, item.Name, err)
+		}
+	}
+	if item.ChartDigest != "" {
+		digest, err := hex.DecodeString(strings.TrimPrefix(item.ChartDigest, "sha256:"))
+		if err != nil || len(digest) != 32 {
+			return fmt.Errorf("invalid chart digest %q for %s, expected a SHA-256 digest", item.ChartDigest, item.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateChartRef checks that a chart reference is a local path, a
+// repo/chart reference or an oci:// reference
+func ValidateChartRef(ref string) error {
+	switch {
+	case ref == "":
+		return errors.New("chart reference is empty")
+	case registry.IsOCI(ref):
+		name := strings.TrimPrefix(ref, fmt.Sprintf("%s://", registry.OCIScheme))
+		if !strings.Contains(name, "/") || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("invalid OCI reference %q", ref)
+		}
+	case strings.Contains(ref, "://"):
+		return fmt.Errorf("unsupported chart reference %q, only oci:// URLs are supported", ref)
+	case strings.HasPrefix(ref, "/"), strings.HasPrefix(ref, "./"), strings.HasPrefix(ref, "../"), strings.HasSuffix(ref, ".tgz"):
+		// Local paths are checked when the chart is resolved
+	case !repoChartRe.MatchString(ref):
+		return fmt.Errorf("invalid chart reference %q, expected a local path, repo/chart or oci://", ref)
+	}
+	return nil
+}
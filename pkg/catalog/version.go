@@ -0,0 +1,99 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// VersionNotFoundError is returned when no version of an app satisfies a
+// pinned version or constraint
+type VersionNotFoundError struct {
+	App        string
+	Group      string
+	Constraint string
+	Available  []string
+}
+
+func (e *VersionNotFoundError) Error() string {
+	available := "none"
+	if len(e.Available) > 0 {
+		available = strings.Join(e.Available, ", ")
+	}
+	return fmt.Sprintf("no version of %s satisfies %q (available: %s)", qualifiedName(e.Group, e.App), e.Constraint, available)
+}
+
+// MatchesVersion reports whether a version satisfies a pin, which may be
+// empty, an exact version or a semver constraint such as ^2.3 or >=1.4 <2.0
+func MatchesVersion(version, pin string) bool {
+	if pin == "" || pin == version || strings.TrimPrefix(pin, "v") == strings.TrimPrefix(version, "v") {
+		return true
+	}
+
+	constraint, err := semver.NewConstraint(pin)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return constraint.Check(v)
+}
+
+// ResolveVersion picks the item with the highest version satisfying the pin.
+// Candidates are ordered oldest first, so when several items share the best
+// version the most recent one wins.
+func ResolveVersion(appName, appGroup, pin string, candidates []CatalogItem) (*CatalogItem, error) {
+	var best *CatalogItem
+	for i := range candidates {
+		if !MatchesVersion(candidates[i].Version, pin) {
+			continue
+		}
+		if best == nil || compareVersions(candidates[i].Version, best.Version) >= 0 {
+			best = &candidates[i]
+		}
+	}
+	if best == nil {
+		return nil, &VersionNotFoundError{App: appName, Group: appGroup, Constraint: pin, Available: availableVersions(candidates)}
+	}
+
+	item := *best
+	return &item, nil
+}
+
+// compareVersions orders versions by semver, versions that do not parse
+// compare as equal
+func compareVersions(a, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return va.Compare(vb)
+}
+
+// availableVersions returns the distinct versions of the candidates, highest first
+func availableVersions(candidates []CatalogItem) []string {
+	seen := make(map[string]bool)
+	var versions []string
+	for _, item := range candidates {
+		if !seen[item.Version] {
+			seen[item.Version] = true
+			versions = append(versions, item.Version)
+		}
+	}
+	sort.SliceStable(versions, func(a, b int) bool {
+		return compareVersions(versions[a], versions[b]) > 0
+	})
+	return versions
+}
+
+func qualifiedName(group, app string) string {
+	if group == "" {
+		return app
+	}
+	return group + "/" + app
+}
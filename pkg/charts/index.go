@@ -0,0 +1,75 @@
+package charts
+
+import (
+	"context"
+	"fmt"
+	"qtm/pkg/catalog"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// IndexCatalog is a catalog.CatalogSource backed by the index.yaml of a Helm
+// chart repository. App names are chart names, and the version of an app is
+// the chart version, so versions never need to be copied into etcd.
+type IndexCatalog struct {
+	Repo     string // Repository name charts are referenced by, as repo/chart
+	Location string // Path or URL the index was read from
+	index    *repo.IndexFile
+}
+
+// NewIndexCatalog reads the index of a chart repository from a file or from
+// an http(s) URL. A URL repository is added to the resolver so the charts it
+// lists can be deployed, an index read from a file needs the repository to be
+// configured for Helm.
+func NewIndexCatalog(ctx context.Context, resolver *Resolver, repoName, location string) (*IndexCatalog, error) {
+	ic := &IndexCatalog{Repo: repoName, Location: location}
+
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		repoURL := strings.TrimSuffix(strings.TrimSuffix(location, "/"), "/index.yaml")
+		if existing, ok := resolver.Repositories[repoName]; ok && strings.TrimSuffix(existing, "/") != repoURL {
+			return nil, fmt.Errorf("repository %s is already configured with %s", repoName, existing)
+		}
+		if resolver.Repositories == nil {
+			resolver.Repositories = make(map[string]string)
+		}
+		resolver.Repositories[repoName] = repoURL
+		ic.index, err = resolver.fetchIndex(ctx, repoName, repoURL)
+	} else {
+		ic.index, err = repo.LoadIndexFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart index %s: %w", location, err)
+	}
+
+	ic.index.SortEntries()
+	return ic, nil
+}
+
+// FetchData resolves an app to a chart version. Without a version the latest
+// stable chart is used, as Helm does.
+func (ic *IndexCatalog) FetchData(appName, appGroup, version string) (*catalog.CatalogItem, error) {
+	versions := ic.index.Entries[appName]
+	if len(versions) == 0 {
+		return nil, &catalog.NotFoundError{App: appName, Group: appGroup, Catalog: ic.Location}
+	}
+	if version == "" {
+		version = "*"
+	}
+
+	// Entries are sorted newest first, candidates are expected oldest first
+	candidates := make([]catalog.CatalogItem, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		cv := versions[i]
+		candidates = append(candidates, catalog.CatalogItem{
+			Name:         appName,
+			Group:        appGroup,
+			Version:      cv.Version,
+			HelmChart:    ic.Repo + "/" + appName,
+			ChartVersion: cv.Version,
+			ChartDigest:  strings.TrimPrefix(cv.Digest, "sha256:"),
+		})
+	}
+	return catalog.ResolveVersion(appName, appGroup, version, candidates)
+}
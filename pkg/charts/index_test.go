@@ -0,0 +1,46 @@
+package charts
+
+import (
+	"context"
+	"errors"
+	"qtm/pkg/catalog"
+	"testing"
+)
+
+func TestIndexCatalog(t *testing.T) {
+	server, _ := newTestRepository(t, false)
+	resolver := NewResolver(t.TempDir(), nil)
+
+	ic, err := NewIndexCatalog(context.Background(), resolver, "stable", server.URL+"/index.yaml")
+	if err != nil {
+		t.Fatalf("NewIndexCatalog failed: %v", err)
+	}
+	if resolver.Repositories["stable"] != server.URL {
+		t.Errorf("Expected the repository to be added to the resolver, got %v", resolver.Repositories)
+	}
+
+	for version, want := range map[string]string{"": "1.2.0", "~1.0": "1.0.0", "<2": "1.2.0"} {
+		item, err := ic.FetchData("web", "frontend", version)
+		if err != nil {
+			t.Fatalf("FetchData(%q) failed: %v", version, err)
+		}
+		if item.Version != want || item.ChartVersion != want || item.HelmChart != "stable/web" || item.ChartDigest == "" {
+			t.Errorf("FetchData(%q) = %+v, want stable/web %s", version, item, want)
+		}
+	}
+
+	// The resolved entry deploys the chart the index describes
+	item, _ := ic.FetchData("web", "frontend", "1.0.0")
+	chart, err := resolver.Resolve(context.Background(), item.HelmChart, item.ChartVersion)
+	if err != nil || chart.Digest != item.ChartDigest {
+		t.Errorf("Resolve = %+v, %v, want digest %s", chart, err, item.ChartDigest)
+	}
+
+	if _, err := ic.FetchData("api", "frontend", ""); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a chart missing from the index, got %v", err)
+	}
+	var versionErr *catalog.VersionNotFoundError
+	if _, err := ic.FetchData("web", "frontend", "^2"); !errors.As(err, &versionErr) {
+		t.Errorf("Expected a VersionNotFoundError, got %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package charts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// ErrDigestMismatch is returned when a downloaded chart does not match the
+// digest published for it
+var ErrDigestMismatch = errors.New("chart digest mismatch")
+
+// Chart is a chart resolved to a local archive or directory
+type Chart struct {
+	Ref     string // Reference the chart was resolved from
+	Version string // Chart version, empty for local charts
+	Path    string // Local path of the chart archive or directory
+	Digest  string // Hex encoded SHA-256 digest of the archive, empty for directories
+}
+
+// Resolver turns chart references into local charts. It understands:
+//
+//   - local paths to a chart directory or packaged archive
+//   - repo/chart, looked up in the index.yaml of a named chart repository
+//   - oci://registry/path/chart references
+//
+// Downloaded charts are kept in the cache directory and verified against the
+// digest published by the repository or registry. Repository indexes are
+// downloaded once for the life of the Resolver. A Resolver is safe for
+// concurrent use once its repositories are set up.
+type Resolver struct {
+	CacheDir     string
+	Repositories map[string]string // Repository names mapped to their URLs
+	HTTPClient   *http.Client
+	Registry     *registry.Client // Used for oci:// references, created on first use when nil
+
+	registryOnce sync.Once
+	registryErr  error
+	indexMu      sync.Mutex
+	indexes      map[string]*repo.IndexFile // Loaded indexes keyed by repository URL
+}
+
+func NewResolver(cacheDir string, repositories map[string]string) *Resolver {
+	return &Resolver{
+		CacheDir:     cacheDir,
+		Repositories: repositories,
+		HTTPClient:   http.DefaultClient,
+	}
+}
+
+// DefaultCacheDir returns the directory charts are cached in by default
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "qtm", "charts")
+}
+
+// LoadRepositories reads the repository names and URLs from a Helm
+// repositories.yaml file. A missing file yields no repositories.
+func LoadRepositories(path string) (map[string]string, error) {
+	file, err := repo.LoadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	repositories := make(map[string]string)
+	if file == nil {
+		return repositories, nil
+	}
+	for _, entry := range file.Repositories {
+		repositories[entry.Name] = entry.URL
+	}
+	return repositories, nil
+}
+
+// Resolve fetches the chart a reference points at. The version may be an
+// exact version or a semver constraint, the latest version is used when empty.
+func (r *Resolver) Resolve(ctx context.Context, ref, version string) (*Chart, error) {
+	switch {
+	case registry.IsOCI(ref):
+		return r.resolveOCI(ref, version)
+	case isLocal(ref):
+		return resolveLocal(ref)
+	}
+
+	name, chartName, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, fmt.Errorf("chart %s is not a local path, repo/chart or oci:// reference", ref)
+	}
+	repoURL, ok := r.Repositories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart repository %q in %s", name, ref)
+	}
+	return r.resolveRepo(ctx, name, repoURL, chartName, version)
+}
+
+// isLocal reports whether a reference names a chart on disk
+func isLocal(ref string) bool {
+	if strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") || strings.HasSuffix(ref, ".tgz") {
+		return true
+	}
+	_, err := os.Stat(ref)
+	return err == nil
+}
+
+func resolveLocal(ref string) (*Chart, error) {
+	info, err := os.Stat(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart %s: %w", ref, err)
+	}
+
+	chart := &Chart{Ref: ref, Path: ref}
+	if !info.IsDir() {
+		if chart.Digest, err = FileDigest(ref); err != nil {
+			return nil, err
+		}
+	}
+	return chart, nil
+}
+
+func (r *Resolver) resolveRepo(ctx context.Context, repoName, repoURL, chartName, version string) (*Chart, error) {
+	index, err := r.fetchIndex(ctx, repoName, repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := index.Get(chartName, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s/%s %s: %w", repoName, chartName, version, err)
+	}
+	if len(entry.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s/%s %s has no download URL", repoName, chartName, entry.Version)
+	}
+
+	chart := &Chart{
+		Ref:     repoName + "/" + chartName,
+		Version: entry.Version,
+		Path:    filepath.Join(r.CacheDir, repoName, fmt.Sprintf("%s-%s.tgz", chartName, entry.Version)),
+		Digest:  strings.TrimPrefix(entry.Digest, "sha256:"),
+	}
+
+	// Reuse the cached archive when it still matches the index
+	if digest, err := FileDigest(chart.Path); err == nil && (chart.Digest == "" || digest == chart.Digest) {
+		chart.Digest = digest
+		return chart, nil
+	}
+
+	chartURL, err := resolveURL(repoURL, entry.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.download(ctx, chartURL)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := Digest(data)
+	if chart.Digest != "" && digest != chart.Digest {
+		return nil, fmt.Errorf("%w: %s/%s %s is %s, index has %s", ErrDigestMismatch, repoName, chartName, entry.Version, digest, chart.Digest)
+	}
+	chart.Digest = digest
+
+	if err := writeFile(chart.Path, data); err != nil {
+		return nil, err
+	}
+	return chart, nil
+}
+
+// fetchIndex downloads the index of a repository into the cache and loads it,
+// reusing the index loaded earlier for the same repository
+func (r *Resolver) fetchIndex(ctx context.Context, repoName, repoURL string) (*repo.IndexFile, error) {
+	r.indexMu.Lock()
+	defer r.indexMu.Unlock()
+	if index, ok := r.indexes[repoURL]; ok {
+		return index, nil
+	}
+
+	indexURL, err := resolveURL(repoURL, "index.yaml")
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.download(ctx, indexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(r.CacheDir, repoName+"-index.yaml")
+	if err := writeFile(path, data); err != nil {
+		return nil, err
+	}
+
+	index, err := repo.LoadIndexFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index of repository %s: %w", repoName, err)
+	}
+
+	if r.indexes == nil {
+		r.indexes = make(map[string]*repo.IndexFile)
+	}
+	r.indexes[repoURL] = index
+	return index, nil
+}
+
+func (r *Resolver) download(ctx context.Context, address string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", address, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", address, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// registryClient returns the registry client, creating it on first use.
+// Charts are resolved from parallel deployments, so it is created only once.
+func (r *Resolver) registryClient() (*registry.Client, error) {
+	r.registryOnce.Do(func() {
+		if r.Registry == nil {
+			r.Registry, r.registryErr = registry.NewClient(registry.ClientOptWriter(io.Discard))
+		}
+	})
+	if r.registryErr != nil {
+		return nil, fmt.Errorf("failed to create registry client: %w", r.registryErr)
+	}
+	return r.Registry, nil
+}
+
+func (r *Resolver) resolveOCI(ref, version string) (*Chart, error) {
+	client, err := r.registryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// A tag in the reference is used when no version is given
+	name := strings.TrimPrefix(ref, fmt.Sprintf("%s://", registry.OCIScheme))
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		if version == "" {
+			version = name[i+1:]
+		}
+		name = name[:i]
+	}
+
+	// Constraints are resolved against the tags of the repository
+	tags, err := client.Tags(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", ref, err)
+	}
+	tag, err := registry.GetTagMatchingVersionOrConstraint(tags, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s %s: %w", ref, version, err)
+	}
+
+	result, err := client.Pull(fmt.Sprintf("%s:%s", name, tag), registry.PullOptWithChart(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+
+	expected := strings.TrimPrefix(result.Chart.Digest, "sha256:")
+	digest := Digest(result.Chart.Data)
+	if digest != expected {
+		return nil, fmt.Errorf("%w: %s:%s is %s, registry has %s", ErrDigestMismatch, name, tag, digest, expected)
+	}
+
+	chart := &Chart{
+		Ref:     ref,
+		Version: tag,
+		Path:    filepath.Join(r.CacheDir, "oci", digest+".tgz"),
+		Digest:  digest,
+	}
+	if err := writeFile(chart.Path, result.Chart.Data); err != nil {
+		return nil, err
+	}
+	return chart, nil
+}
+
+// Digest returns the hex encoded SHA-256 digest of data
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FileDigest returns the hex encoded SHA-256 digest of a file
+func FileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// resolveURL resolves a possibly relative chart URL against the repository URL
+func resolveURL(repoURL, ref string) (string, error) {
+	base, err := url.Parse(strings.TrimSuffix(repoURL, "/") + "/")
+	if err != nil {
+		return "", fmt.Errorf("invalid repository URL %s: %w", repoURL, err)
+	}
+	target, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid chart URL %s: %w", ref, err)
+	}
+	return base.ResolveReference(target).String(), nil
+}
+
+// writeFile writes data through a temporary file so a partially written
+// chart is never picked up from the cache
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
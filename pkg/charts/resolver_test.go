@@ -0,0 +1,156 @@
+package charts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// downloadCounts counts the requests a test repository served
+type downloadCounts struct {
+	charts  int32
+	indexes int32
+}
+
+// newTestRepository serves an index.yaml with two versions of a chart
+func newTestRepository(t *testing.T, badDigest bool) (*httptest.Server, *downloadCounts) {
+	t.Helper()
+
+	dir := t.TempDir()
+	archives := make(map[string][]byte)
+	digests := make(map[string]string)
+	for _, version := range []string{"1.0.0", "1.2.0"} {
+		path, err := chartutil.Save(&chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "web", Version: version},
+		}, dir)
+		if err != nil {
+			t.Fatalf("Failed to package chart: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives[filepath.Base(path)] = data
+		digests[version] = Digest(data)
+	}
+	if badDigest {
+		digests["1.2.0"] = Digest([]byte("tampered"))
+	}
+
+	index := fmt.Sprintf(`apiVersion: v1
+entries:
+  web:
+  - apiVersion: v2
+    name: web
+    version: 1.2.0
+    digest: %s
+    urls: [charts/web-1.2.0.tgz]
+  - apiVersion: v2
+    name: web
+    version: 1.0.0
+    digest: %s
+    urls: [charts/web-1.0.0.tgz]
+`, digests["1.2.0"], digests["1.0.0"])
+
+	var downloads downloadCounts
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads.indexes, 1)
+		w.Write([]byte(index))
+	})
+	mux.HandleFunc("/charts/", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := archives[filepath.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&downloads.charts, 1)
+		w.Write(data)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &downloads
+}
+
+func TestResolveRepository(t *testing.T) {
+	server, downloads := newTestRepository(t, false)
+	resolver := NewResolver(t.TempDir(), map[string]string{"stable": server.URL})
+	ctx := context.Background()
+
+	latest, err := resolver.Resolve(ctx, "stable/web", "")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if latest.Version != "1.2.0" {
+		t.Errorf("Version = %s, want 1.2.0", latest.Version)
+	}
+	if digest, _ := FileDigest(latest.Path); digest != latest.Digest {
+		t.Errorf("Cached chart digest = %s, want %s", digest, latest.Digest)
+	}
+
+	constrained, err := resolver.Resolve(ctx, "stable/web", "~1.0")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if constrained.Version != "1.0.0" {
+		t.Errorf("Version = %s, want 1.0.0", constrained.Version)
+	}
+
+	// Charts already in the cache are not downloaded again
+	if _, err := resolver.Resolve(ctx, "stable/web", "1.2.0"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&downloads.charts); got != 2 {
+		t.Errorf("Downloaded %d charts, want 2", got)
+	}
+	if got := atomic.LoadInt32(&downloads.indexes); got != 1 {
+		t.Errorf("Downloaded the index %d times, want once", got)
+	}
+
+	if _, err := resolver.Resolve(ctx, "stable/web", "2.x"); err == nil {
+		t.Errorf("Expected an error for a version that does not exist")
+	}
+	if _, err := resolver.Resolve(ctx, "unknown/web", ""); err == nil {
+		t.Errorf("Expected an error for an unknown repository")
+	}
+}
+
+func TestResolveDigestMismatch(t *testing.T) {
+	server, _ := newTestRepository(t, true)
+	resolver := NewResolver(t.TempDir(), map[string]string{"stable": server.URL})
+
+	_, err := resolver.Resolve(context.Background(), "stable/web", "1.2.0")
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("Expected a digest mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(resolver.CacheDir, "stable", "web-1.2.0.tgz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the mismatched chart not to be cached")
+	}
+}
+
+func TestResolveLocal(t *testing.T) {
+	path, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "local", Version: "0.1.0"},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to package chart: %v", err)
+	}
+
+	resolved, err := NewResolver(t.TempDir(), nil).Resolve(context.Background(), path, "")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if resolved.Path != path || resolved.Digest == "" {
+		t.Errorf("Unexpected local chart %+v", resolved)
+	}
+}
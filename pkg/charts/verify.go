@@ -0,0 +1,113 @@
+package charts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"qtm/pkg/catalog"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// Verification is the outcome of checking a chart against its catalog entry
+type Verification struct {
+	Chart              string   // Reference of the chart
+	Version            string   // Resolved chart version
+	Path               string   // Local path of the verified chart
+	Digest             string   // SHA-256 digest of the chart archive
+	DigestVerified     bool     // The digest matched the catalog entry
+	ProvenanceVerified bool     // The provenance signature was valid and matched the archive
+	SignedBy           []string // Identities of the key that signed the provenance file
+}
+
+// Verifier resolves the chart of a catalog entry and checks it against the
+// digest and provenance file the catalog approved
+type Verifier struct {
+	Resolver *Resolver
+	Keyring  string // Public keyring used to check provenance signatures
+}
+
+func NewVerifier(resolver *Resolver, keyring string) *Verifier {
+	return &Verifier{Resolver: resolver, Keyring: keyring}
+}
+
+// Verify resolves and checks the chart of a catalog entry. The verification
+// is returned alongside any error so callers can record what was checked.
+// Entries without a digest or provenance file are not resolved at all.
+func (v *Verifier) Verify(ctx context.Context, item catalog.CatalogItem) (Verification, error) {
+	result := Verification{Chart: item.HelmChart}
+	if item.ChartDigest == "" && item.Provenance == "" {
+		return result, nil
+	}
+
+	chart, err := v.Resolver.Resolve(ctx, item.HelmChart, item.ChartVersion)
+	if err != nil {
+		return result, err
+	}
+	result.Version = chart.Version
+	result.Path = chart.Path
+	result.Digest = chart.Digest
+
+	if item.ChartDigest != "" {
+		if chart.Digest == "" {
+			return result, fmt.Errorf("cannot verify the digest of chart directory %s", chart.Path)
+		}
+		expected := strings.ToLower(strings.TrimPrefix(item.ChartDigest, "sha256:"))
+		if chart.Digest != expected {
+			return result, fmt.Errorf("%w: %s is %s, catalog approved %s", ErrDigestMismatch, item.HelmChart, chart.Digest, expected)
+		}
+		result.DigestVerified = true
+	}
+
+	if item.Provenance != "" {
+		signedBy, err := v.verifyProvenance(ctx, chart, item.Provenance)
+		if err != nil {
+			return result, fmt.Errorf("provenance verification of %s failed: %w", item.HelmChart, err)
+		}
+		result.ProvenanceVerified = true
+		result.SignedBy = signedBy
+	}
+
+	return result, nil
+}
+
+func (v *Verifier) verifyProvenance(ctx context.Context, chart *Chart, ref string) ([]string, error) {
+	if v.Keyring == "" {
+		return nil, errors.New("no keyring configured")
+	}
+	if chart.Digest == "" {
+		return nil, fmt.Errorf("chart directory %s cannot be signed", chart.Path)
+	}
+
+	// Provenance files may be local or served next to the chart
+	path := ref
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		data, err := v.Resolver.download(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(v.Resolver.CacheDir, "prov", chart.Digest+".prov")
+		if err := writeFile(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	signatory, err := provenance.NewFromKeyring(v.Keyring, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keyring %s: %w", v.Keyring, err)
+	}
+	verification, err := signatory.Verify(chart.Path, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var signedBy []string
+	for name := range verification.SignedBy.Identities {
+		signedBy = append(signedBy, name)
+	}
+	sort.Strings(signedBy)
+	return signedBy, nil
+}
@@ -0,0 +1,86 @@
+package charts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"qtm/pkg/catalog"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// signChart packages a chart, signs it with a new key and returns the paths
+// of the archive, its provenance file and a keyring holding the public key
+func signChart(t *testing.T) (string, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	chartPath, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "signed", Version: "1.0.0"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("Failed to package chart: %v", err)
+	}
+
+	entity, err := openpgp.NewEntity("qtm test", "", "qtm@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	signatory := &provenance.Signatory{Entity: entity, KeyRing: openpgp.EntityList{entity}}
+	signature, err := signatory.ClearSign(chartPath)
+	if err != nil {
+		t.Fatalf("Failed to sign chart: %v", err)
+	}
+	provPath := chartPath + ".prov"
+	if err := os.WriteFile(provPath, []byte(signature), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := os.Create(filepath.Join(dir, "pubring.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keyring.Close()
+	if err := entity.Serialize(keyring); err != nil {
+		t.Fatalf("Failed to write keyring: %v", err)
+	}
+
+	return chartPath, provPath, keyring.Name()
+}
+
+func TestVerify(t *testing.T) {
+	chartPath, provPath, keyring := signChart(t)
+	digest, err := FileDigest(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewVerifier(NewResolver(t.TempDir(), nil), keyring)
+	ctx := context.Background()
+
+	verification, err := verifier.Verify(ctx, catalog.CatalogItem{Name: "signed", HelmChart: chartPath, ChartDigest: "sha256:" + digest, Provenance: provPath})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !verification.DigestVerified || !verification.ProvenanceVerified {
+		t.Errorf("Expected digest and provenance to be verified, got %+v", verification)
+	}
+	if len(verification.SignedBy) != 1 {
+		t.Errorf("Expected one signer, got %v", verification.SignedBy)
+	}
+
+	_, err = verifier.Verify(ctx, catalog.CatalogItem{Name: "signed", HelmChart: chartPath, ChartDigest: Digest([]byte("other"))})
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Expected a digest mismatch, got %v", err)
+	}
+
+	// A chart signed by a key outside the keyring must be refused
+	otherPath, otherProv, _ := signChart(t)
+	if _, err := verifier.Verify(ctx, catalog.CatalogItem{Name: "signed", HelmChart: otherPath, Provenance: otherProv}); err == nil {
+		t.Errorf("Expected a chart signed by an unknown key to be refused")
+	}
+}
@@ -2,31 +2,28 @@ package deployment
 
 import (
 	"context"
+	"fmt"
 	"qtm/pkg/catalog"
+	"qtm/pkg/charts"
 	"qtm/pkg/session"
 	"qtm/pkg/suite"
+	"qtm/pkg/values"
+	"time"
 )
 
 // DeploymentResult represents the result of a deployment attempt
 type DeploymentResult struct {
 	AppID    string
 	Phase    int
+	Version  string
 	Status   DeploymentStatus
 	ErrorMsg string
+	Attempts int // Number of deployment attempts, more than one when retried
 }
 
-// DeploymentStatus represents the status of a deployment
-type DeploymentStatus int
-
-const (
-	Pending DeploymentStatus = iota
-	Success
-	Fail
-)
-
 // Deployer defines the interface for deploying applications
 type Deployer interface {
-	Deploy(ctx context.Context, app suite.SuiteItem, data catalog.CatalogItem, phase int) DeploymentResult
+	Deploy(ctx context.Context, app suite.SuiteItem, data catalog.CatalogItem, vals values.Values, phase int) DeploymentResult
 	SetSessionManager(manager session.SessionManager)
 	GetSessionManager() session.SessionManager
 	SetCatalogSource(src catalog.CatalogSource)
@@ -35,29 +32,144 @@ type Deployer interface {
 	GetSuiteSource() suite.SuiteSource
 }
 
-// deployApp is a function to handle the deployment of a single app
-func DeployApp(ctx context.Context, d Deployer, app suite.SuiteItem, phase int, results chan<- DeploymentResult) {
+// ChartVerifier checks the chart of a catalog entry before it is deployed
+type ChartVerifier interface {
+	Verify(ctx context.Context, item catalog.CatalogItem) (charts.Verification, error)
+}
+
+// AppOptions holds the rollout wide settings used when deploying each app
+type AppOptions struct {
+	Values   values.Layers // Suite and environment values merged into the values of every app
+	Verifier ChartVerifier // Checks charts before they are deployed, nothing is checked when nil
+}
+
+// deployApp is a function to handle the deployment of a single app. The Helm
+// values of the app are merged from the catalog, the value layers and the item.
+func DeployApp(ctx context.Context, d Deployer, app suite.SuiteItem, phase int, opts AppOptions, results chan<- DeploymentResult) {
 	// Check for cancellation before starting deployment
 	if ctx.Err() != nil {
+		results <- DeploymentResult{AppID: app.Name, Phase: phase, Status: StatusFromContext(ctx.Err()), ErrorMsg: ctx.Err().Error()}
 		return
 	}
 
 	// Fetch version and chart for the app from the catalog
 	catalogSource := d.GetCatalogSource()
-	data, err := catalogSource.FetchData(app.Name, app.Group)
+	data, err := catalogSource.FetchData(app.Name, app.Group, app.Version)
 	if err != nil {
 		results <- DeploymentResult{AppID: app.Name, Phase: phase, Status: Fail, ErrorMsg: err.Error()}
 		return
 	}
 
+	vals, err := opts.Values.For(data.Source, app.Source)
+	if err != nil {
+		results <- DeploymentResult{AppID: app.Name, Phase: phase, Version: data.Version, Status: Fail, ErrorMsg: err.Error()}
+		return
+	}
+
+	// Nothing to do if the session already holds the app at this version and values
+	sessionManager := d.GetSessionManager()
+	if isDeployed(sessionManager, app.Name, data.Version, vals) {
+		sessionManager.UpdateAppStatus(app.Name, Unchanged.String())
+		results <- DeploymentResult{AppID: app.Name, Phase: phase, Version: data.Version, Status: Unchanged}
+		return
+	}
+
+	// Refuse to deploy a chart that does not match what the catalog approved
+	var verification *session.ChartVerification
+	if opts.Verifier != nil {
+		verified, err := opts.Verifier.Verify(ctx, *data)
+		verification = verificationRecord(data.Version, verified, err)
+
+		// The app entry keeps the verification of the version still running,
+		// so every outcome is also recorded on its own
+		recordErr := sessionManager.RecordVerification(app.Name, *verification)
+		if err != nil {
+			msg := err.Error()
+			if recordErr != nil {
+				msg = fmt.Sprintf("%s (failed to record verification: %v)", msg, recordErr)
+			}
+			results <- DeploymentResult{AppID: app.Name, Phase: phase, Version: data.Version, Status: Fail, ErrorMsg: msg}
+			return
+		}
+	}
+
 	// Perform the actual deployment as part of this instantiation of the deployer
-	result := d.Deploy(ctx, app, *data, phase)
+	result := deployWithRetries(ctx, d, app, *data, vals, phase)
+	result.Version = data.Version
+	if err := Pending.ValidateTransition(result.Status); err != nil {
+		result.Status = Fail
+		result.ErrorMsg = err.Error()
+	}
 
 	// Add the app to the session if the deployment was successful
 	if result.Status == Success {
-		d.GetSessionManager().AddApp(app, data.Version) // Add the app to the session
+		sessionManager.AddApp(app, data.Version) // Add the app to the session
+		sessionManager.UpdateAppStatus(app.Name, result.Status.String())
+		sessionManager.UpdateAppValues(app.Name, vals)
+		if data.Origin != "" {
+			sessionManager.UpdateAppOrigin(app.Name, data.Origin)
+		}
+		if verification != nil {
+			sessionManager.UpdateAppVerification(app.Name, *verification)
+		}
 	}
 
 	// Send the result to the results channel
 	results <- result
 }
+
+// deployWithRetries limits each deployment attempt to the timeout of the item
+// and retries failed and timed out attempts as many times as the item allows
+func deployWithRetries(ctx context.Context, d Deployer, app suite.SuiteItem, data catalog.CatalogItem, vals values.Values, phase int) DeploymentResult {
+	var result DeploymentResult
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if app.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, app.Timeout)
+		}
+		result = d.Deploy(attemptCtx, app, data, vals, phase)
+		cancel()
+
+		result.Attempts = attempt
+		retryable := result.Status == Fail || result.Status == TimedOut
+		if !retryable || attempt > app.RetryCount() || ctx.Err() != nil {
+			return result
+		}
+	}
+}
+
+// isDeployed reports whether the session already holds the app at the given
+// version and values. Apps recorded before values were tracked match when no
+// values are set.
+func isDeployed(sm session.SessionManager, appName, version string, vals values.Values) bool {
+	apps, err := sm.GetApps()
+	if err != nil {
+		return false
+	}
+	app, ok := apps[appName]
+	if !ok || app.Version != version {
+		return false
+	}
+	if app.ValuesDigest == "" {
+		return len(vals) == 0
+	}
+	return app.ValuesDigest == values.Digest(vals)
+}
+
+// verificationRecord converts the outcome of a chart verification into its session record
+func verificationRecord(appVersion string, v charts.Verification, err error) *session.ChartVerification {
+	record := &session.ChartVerification{
+		AppVersion:         appVersion,
+		Chart:              v.Chart,
+		Version:            v.Version,
+		Digest:             v.Digest,
+		DigestVerified:     v.DigestVerified,
+		ProvenanceVerified: v.ProvenanceVerified,
+		SignedBy:           v.SignedBy,
+		VerifiedAt:         time.Now(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
+}
@@ -0,0 +1,55 @@
+package deployment
+
+import (
+	"context"
+	"qtm/pkg/catalog"
+	"qtm/pkg/suite"
+	"qtm/pkg/values"
+	"testing"
+	"time"
+)
+
+// flakyDeployer fails a number of attempts before succeeding
+type flakyDeployer struct {
+	MockDeployer
+	failures int
+	attempts int
+}
+
+func (f *flakyDeployer) Deploy(ctx context.Context, app suite.SuiteItem, data catalog.CatalogItem, vals values.Values, phase int) DeploymentResult {
+	f.attempts++
+	if f.attempts <= f.failures {
+		if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+			return DeploymentResult{AppID: app.Name, Phase: phase, Status: Fail, ErrorMsg: "no deadline"}
+		}
+		<-ctx.Done()
+		return DeploymentResult{AppID: app.Name, Phase: phase, Status: StatusFromContext(ctx.Err()), ErrorMsg: ctx.Err().Error()}
+	}
+	return DeploymentResult{AppID: app.Name, Phase: phase, Status: Success}
+}
+
+func TestDeployWithRetries(t *testing.T) {
+	retries := 2
+	app := suite.SuiteItem{Name: "app1", Timeout: 10 * time.Millisecond, Retries: &retries}
+
+	tests := []struct {
+		name     string
+		failures int
+		want     DeploymentStatus
+		attempts int
+	}{
+		{name: "First attempt succeeds", failures: 0, want: Success, attempts: 1},
+		{name: "Timed out attempts retried", failures: 2, want: Success, attempts: 3},
+		{name: "Retries exhausted", failures: 3, want: TimedOut, attempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &flakyDeployer{failures: tt.failures}
+			result := deployWithRetries(context.Background(), d, app, catalog.CatalogItem{}, nil, 1)
+			if result.Status != tt.want || result.Attempts != tt.attempts {
+				t.Errorf("Result = %s after %d attempts, want %s after %d", result.Status, result.Attempts, tt.want, tt.attempts)
+			}
+		})
+	}
+}
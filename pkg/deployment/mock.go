@@ -5,6 +5,7 @@ import (
 	"qtm/pkg/catalog"
 	"qtm/pkg/session"
 	"qtm/pkg/suite"
+	"qtm/pkg/values"
 	"sync"
 	"time"
 
@@ -47,7 +48,7 @@ func (m *MockDeployer) SetDeploymentResult(appID string, phase int, result Deplo
 }
 
 // Deploy simulates deploying an app in a phase
-func (m *MockDeployer) Deploy(ctx context.Context, app suite.SuiteItem, data catalog.CatalogItem, phase int) DeploymentResult {
+func (m *MockDeployer) Deploy(ctx context.Context, app suite.SuiteItem, data catalog.CatalogItem, vals values.Values, phase int) DeploymentResult {
 	m.mu.Lock()
 	if result, exists := m.checkPredefinedResult(app.Name, phase); exists {
 		m.mu.Unlock()
@@ -57,11 +58,11 @@ func (m *MockDeployer) Deploy(ctx context.Context, app suite.SuiteItem, data cat
 
 	// Check for cancellation before starting deployment
 	if ctx.Err() != nil {
-		return DeploymentResult{AppID: app.Name, Phase: phase, Status: Fail, ErrorMsg: ctx.Err().Error()}
+		return DeploymentResult{AppID: app.Name, Phase: phase, Status: StatusFromContext(ctx.Err()), ErrorMsg: ctx.Err().Error()}
 	}
 
 	// Perform the actual deployment as part of this instantiation of the deployer
-	m.logger.Info("Mocking deploy", zap.String("appID", app.Name), zap.Int("phase", phase), zap.String("version", data.Version), zap.String("chart", data.Name))
+	m.logger.Info("Mocking deploy", zap.String("appID", app.Name), zap.Int("phase", phase), zap.String("version", data.Version), zap.String("chart", data.Name), zap.String("values", values.Digest(vals)), zap.String("catalog", data.Origin))
 
 	// Check for predefined results first
 	if result, exists := m.checkPredefinedResult(app.Name, phase); exists {
@@ -72,7 +73,7 @@ func (m *MockDeployer) Deploy(ctx context.Context, app suite.SuiteItem, data cat
 	}
 
 	if ctx.Err() != nil {
-		return DeploymentResult{AppID: app.Name, Phase: phase, Status: Fail, ErrorMsg: ctx.Err().Error()}
+		return DeploymentResult{AppID: app.Name, Phase: phase, Status: StatusFromContext(ctx.Err()), ErrorMsg: ctx.Err().Error()}
 	}
 
 	m.logger.Info("Mock deploy completed", zap.String("appID", app.Name), zap.Int("phase", phase), zap.String("version", data.Version), zap.String("chart", data.HelmChart))
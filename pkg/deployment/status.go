@@ -0,0 +1,123 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DeploymentStatus represents the status of a deployment
+type DeploymentStatus int
+
+const (
+	Pending   DeploymentStatus = iota
+	Success                    // The app was deployed at the requested version
+	Fail                       // The deployment was attempted and failed
+	Skipped                    // The deployment was not attempted
+	Cancelled                  // The deployment was interrupted by a cancelled context
+	TimedOut                   // The deployment did not finish before its deadline
+	Unchanged                  // The app was already running the requested version
+)
+
+var deploymentStatusNames = map[DeploymentStatus]string{
+	Pending:   "Pending",
+	Success:   "Success",
+	Fail:      "Fail",
+	Skipped:   "Skipped",
+	Cancelled: "Cancelled",
+	TimedOut:  "TimedOut",
+	Unchanged: "Unchanged",
+}
+
+// deploymentTransitions lists the statuses each status may move to. Failed,
+// cancelled and timed out deployments may be retried by moving back to Pending.
+var deploymentTransitions = map[DeploymentStatus][]DeploymentStatus{
+	Pending:   {Success, Fail, Skipped, Cancelled, TimedOut, Unchanged},
+	Fail:      {Pending},
+	Cancelled: {Pending},
+	TimedOut:  {Pending},
+}
+
+// String returns the name of the status
+func (s DeploymentStatus) String() string {
+	if name, ok := deploymentStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("DeploymentStatus(%d)", int(s))
+}
+
+// ParseDeploymentStatus converts a status name back into a DeploymentStatus
+func ParseDeploymentStatus(name string) (DeploymentStatus, error) {
+	for status, n := range deploymentStatusNames {
+		if n == name {
+			return status, nil
+		}
+	}
+	return Pending, fmt.Errorf("unknown deployment status %q", name)
+}
+
+// IsTerminal reports whether the status is a final outcome of a deployment attempt
+func (s DeploymentStatus) IsTerminal() bool {
+	return s != Pending
+}
+
+// IsFailure reports whether the status should count against the phase
+func (s DeploymentStatus) IsFailure() bool {
+	return s == Fail || s == Cancelled || s == TimedOut || s == Pending
+}
+
+// CanTransitionTo reports whether moving from s to next is allowed
+func (s DeploymentStatus) CanTransitionTo(next DeploymentStatus) bool {
+	for _, allowed := range deploymentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns an error if moving from s to next is not allowed
+func (s DeploymentStatus) ValidateTransition(next DeploymentStatus) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("invalid deployment status transition from %s to %s", s, next)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the status as its name
+func (s DeploymentStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes the status from its name, or from its numeric value
+// as written by older versions of qtm
+func (s *DeploymentStatus) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var value int
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("deployment status must be a string: %w", err)
+		}
+		if _, ok := deploymentStatusNames[DeploymentStatus(value)]; !ok {
+			return fmt.Errorf("unknown deployment status %d", value)
+		}
+		*s = DeploymentStatus(value)
+		return nil
+	}
+
+	status, err := ParseDeploymentStatus(name)
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// StatusFromContext maps a context error onto the matching deployment status
+func StatusFromContext(err error) DeploymentStatus {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return TimedOut
+	}
+	return Cancelled
+}
@@ -0,0 +1,60 @@
+package deployment
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDeploymentStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    DeploymentStatus
+		to      DeploymentStatus
+		allowed bool
+	}{
+		{name: "Pending to Success", from: Pending, to: Success, allowed: true},
+		{name: "Pending to Unchanged", from: Pending, to: Unchanged, allowed: true},
+		{name: "Pending to Pending", from: Pending, to: Pending, allowed: false},
+		{name: "Fail retried", from: Fail, to: Pending, allowed: true},
+		{name: "TimedOut retried", from: TimedOut, to: Pending, allowed: true},
+		{name: "Success is final", from: Success, to: Fail, allowed: false},
+		{name: "Skipped is final", from: Skipped, to: Pending, allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.allowed {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.allowed)
+			}
+			if err := tt.from.ValidateTransition(tt.to); (err == nil) != tt.allowed {
+				t.Errorf("%s.ValidateTransition(%s) error = %v", tt.from, tt.to, err)
+			}
+		})
+	}
+}
+
+func TestDeploymentStatusJSON(t *testing.T) {
+	result := DeploymentResult{AppID: "app1", Phase: 1, Status: TimedOut}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded DeploymentResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Status != TimedOut {
+		t.Errorf("Status = %s, want %s", decoded.Status, TimedOut)
+	}
+
+	var legacy DeploymentStatus
+	if err := json.Unmarshal([]byte("2"), &legacy); err != nil || legacy != Fail {
+		t.Errorf("Unmarshal legacy value = %s, %v, want %s", legacy, err, Fail)
+	}
+
+	if err := json.Unmarshal([]byte(`"Exploded"`), &legacy); err == nil {
+		t.Errorf("Expected an error for an unknown status name")
+	}
+}
@@ -0,0 +1,245 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/yaml"
+)
+
+// Change describes how a resource differs from the live release
+type Change string
+
+const (
+	ChangeAdded     Change = "added"
+	ChangeRemoved   Change = "removed"
+	ChangeModified  Change = "modified"
+	ChangeUnchanged Change = "unchanged"
+)
+
+// ResourceDiff is the difference of a single Kubernetes resource
+type ResourceDiff struct {
+	Resource string `json:"resource"`
+	Change   Change `json:"change"`
+	Diff     string `json:"diff,omitempty"`
+}
+
+// Differ renders charts and compares them with the deployed Helm releases
+type Differ struct {
+	config    *action.Configuration
+	namespace string
+}
+
+func NewDiffer(config *action.Configuration, namespace string) *Differ {
+	return &Differ{config: config, namespace: namespace}
+}
+
+// LoadChart loads a chart from a local directory or packaged archive
+func LoadChart(ref string) (*chart.Chart, error) {
+	return loader.Load(ref)
+}
+
+// Diff renders the chart for the release and compares every resource with
+// the manifest of the currently deployed release. A release that is not
+// deployed yet shows every resource as added.
+func (d *Differ) Diff(releaseName string, chrt *chart.Chart, values map[string]interface{}) ([]ResourceDiff, error) {
+	live := ""
+	isUpgrade := false
+	rel, err := d.config.Releases.Deployed(releaseName)
+	switch {
+	case err == nil:
+		live = rel.Manifest
+		isUpgrade = true
+	case errors.Is(err, driver.ErrNoDeployedReleases), errors.Is(err, driver.ErrReleaseNotFound):
+	default:
+		return nil, fmt.Errorf("failed to fetch release %s: %w", releaseName, err)
+	}
+
+	rendered, err := d.Render(releaseName, chrt, values, isUpgrade)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := splitResources(live, d.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of release %s: %w", releaseName, err)
+	}
+	desired, err := splitResources(rendered, d.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rendered manifest of %s: %w", releaseName, err)
+	}
+
+	return compare(current, desired), nil
+}
+
+// Render renders the chart templates as a single manifest in the same way
+// helm install and helm upgrade would
+func (d *Differ) Render(releaseName string, chrt *chart.Chart, values map[string]interface{}, isUpgrade bool) (string, error) {
+	if err := chartutil.ProcessDependencies(chrt, values); err != nil {
+		return "", fmt.Errorf("failed to process dependencies of %s: %w", chrt.Name(), err)
+	}
+
+	options := chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: d.namespace,
+		Revision:  1,
+		IsInstall: !isUpgrade,
+		IsUpgrade: isUpgrade,
+	}
+	renderValues, err := chartutil.ToRenderValues(chrt, values, options, chartutil.DefaultCapabilities)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := engine.Render(chrt, renderValues)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", chrt.Name(), err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		base := path.Base(name)
+		if strings.HasPrefix(base, "_") || strings.HasSuffix(base, "NOTES.txt") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifest strings.Builder
+	for _, name := range names {
+		if strings.TrimSpace(files[name]) == "" {
+			continue
+		}
+		fmt.Fprintf(&manifest, "---\n# Source: %s\n%s\n", name, files[name])
+	}
+	return manifest.String(), nil
+}
+
+// resourceHeader holds the fields used to identify a resource
+type resourceHeader struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// splitResources splits a manifest into documents keyed by
+// kind/namespace/name
+func splitResources(manifest, namespace string) (map[string]string, error) {
+	resources := make(map[string]string)
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var header resourceHeader
+		if err := yaml.Unmarshal([]byte(doc), &header); err != nil {
+			return nil, err
+		}
+		if header.Kind == "" {
+			continue
+		}
+		ns := header.Metadata.Namespace
+		if ns == "" {
+			ns = namespace
+		}
+		key := fmt.Sprintf("%s/%s/%s", header.Kind, ns, header.Metadata.Name)
+		resources[key] = stripSource(doc)
+	}
+	return resources, nil
+}
+
+// stripSource drops the "# Source:" comments helm adds so moving a resource
+// between template files does not show up as a change
+func stripSource(doc string) string {
+	lines := strings.Split(strings.TrimSpace(doc), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "# Source: ") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n") + "\n"
+}
+
+func compare(current, desired map[string]string) []ResourceDiff {
+	keys := make(map[string]struct{})
+	for key := range current {
+		keys[key] = struct{}{}
+	}
+	for key := range desired {
+		keys[key] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	diffs := make([]ResourceDiff, 0, len(sorted))
+	for _, key := range sorted {
+		before, inCurrent := current[key]
+		after, inDesired := desired[key]
+
+		change := ChangeModified
+		switch {
+		case !inCurrent:
+			change = ChangeAdded
+		case !inDesired:
+			change = ChangeRemoved
+		case before == after:
+			change = ChangeUnchanged
+		}
+
+		result := ResourceDiff{Resource: key, Change: change}
+		if change != ChangeUnchanged {
+			result.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(before),
+				B:        difflib.SplitLines(after),
+				FromFile: "live/" + key,
+				ToFile:   "rendered/" + key,
+				Context:  3,
+			})
+		}
+		diffs = append(diffs, result)
+	}
+	return diffs
+}
+
+// HasChanges reports whether any resource differs
+func HasChanges(diffs []ResourceDiff) bool {
+	for _, d := range diffs {
+		if d.Change != ChangeUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints the diff of every changed resource
+func Write(out io.Writer, releaseName string, diffs []ResourceDiff) error {
+	if !HasChanges(diffs) {
+		_, err := fmt.Fprintf(out, "%s: no changes\n", releaseName)
+		return err
+	}
+	for _, d := range diffs {
+		if d.Change == ChangeUnchanged {
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "%s: %s %s\n%s", releaseName, d.Resource, d.Change, d.Diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
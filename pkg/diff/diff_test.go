@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+const configMapTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-config
+data:
+  level: {{ .Values.level | quote }}
+`
+
+const serviceTemplate = `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  ports:
+  - port: 80
+`
+
+func testChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "0.1.0"},
+		Values:   map[string]interface{}{"level": "info"},
+		Templates: []*chart.File{
+			{Name: "templates/configmap.yaml", Data: []byte(configMapTemplate)},
+			{Name: "templates/service.yaml", Data: []byte(serviceTemplate)},
+			{Name: "templates/NOTES.txt", Data: []byte("Installed {{ .Release.Name }}")},
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	store := storage.Init(driver.NewMemory())
+	config := &action.Configuration{Releases: store}
+	differ := NewDiffer(config, "default")
+
+	// A release that is not deployed shows every resource as added
+	diffs, err := differ.Diff("web", testChart(), nil)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].Change != ChangeAdded || diffs[1].Change != ChangeAdded {
+		t.Fatalf("Expected two added resources, got %+v", diffs)
+	}
+
+	// Deploy the rendered chart with a resource that the chart no longer has
+	live, err := differ.Render("web", testChart(), nil, false)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	live += "---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: web-old\n"
+	rel := &release.Release{Name: "web", Namespace: "default", Version: 1, Manifest: live, Info: &release.Info{Status: release.StatusDeployed}}
+	if err := store.Create(rel); err != nil {
+		t.Fatalf("Failed to store release: %v", err)
+	}
+
+	diffs, err = differ.Diff("web", testChart(), map[string]interface{}{"level": "debug"})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	changes := make(map[string]ResourceDiff)
+	for _, d := range diffs {
+		changes[d.Resource] = d
+	}
+	expected := map[string]Change{
+		"ConfigMap/default/web-config": ChangeModified,
+		"Secret/default/web-old":       ChangeRemoved,
+		"Service/default/web":          ChangeUnchanged,
+	}
+	for resource, want := range expected {
+		if got := changes[resource].Change; got != want {
+			t.Errorf("%s: change = %q, want %q", resource, got, want)
+		}
+	}
+
+	configDiff := changes["ConfigMap/default/web-config"].Diff
+	if !strings.Contains(configDiff, `-  level: "info"`) || !strings.Contains(configDiff, `+  level: "debug"`) {
+		t.Errorf("Unexpected diff:\n%s", configDiff)
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, "web", diffs); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if strings.Contains(out.String(), "Service/default/web") {
+		t.Errorf("Unchanged resources should not be printed:\n%s", out.String())
+	}
+}
@@ -0,0 +1,143 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"qtm/pkg/suite"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxOutput bounds how much hook output is kept for the session
+const maxOutput = 16 * 1024
+
+// Result is the outcome of running a single hook
+type Result struct {
+	Hook       suite.HookConfig
+	Output     string
+	Err        error
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Runner executes lifecycle hooks
+type Runner struct {
+	Kube             kubernetes.Interface // Client used by job hooks, may be nil if none are configured
+	HTTPClient       *http.Client
+	DefaultNamespace string
+	JobPollInterval  time.Duration
+}
+
+// NewRunner creates a Runner with a default HTTP client
+func NewRunner(kube kubernetes.Interface, namespace string) *Runner {
+	return &Runner{
+		Kube:             kube,
+		HTTPClient:       &http.Client{Timeout: 30 * time.Second},
+		DefaultNamespace: namespace,
+		JobPollInterval:  2 * time.Second,
+	}
+}
+
+// Run executes the hook and captures its output
+func (r *Runner) Run(ctx context.Context, hook suite.HookConfig) Result {
+	result := Result{Hook: hook, StartedAt: time.Now()}
+
+	if hook.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+		defer cancel()
+	}
+
+	switch hook.Type {
+	case suite.HookCommand:
+		result.Output, result.Err = r.runCommand(ctx, hook)
+	case suite.HookJob:
+		result.Output, result.Err = r.runJob(ctx, hook)
+	case suite.HookHTTP:
+		result.Output, result.Err = r.runHTTP(ctx, hook)
+	default:
+		result.Err = fmt.Errorf("unknown hook type %q", hook.Type)
+	}
+
+	result.Output = truncate(result.Output)
+	result.FinishedAt = time.Now()
+	return result
+}
+
+func (r *Runner) runCommand(ctx context.Context, hook suite.HookConfig) (string, error) {
+	if len(hook.Command) == 0 {
+		return "", fmt.Errorf("hook %s: command is required", hook.Name)
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		return output.String(), fmt.Errorf("hook %s: %s: %w", hook.Name, strings.Join(hook.Command, " "), err)
+	}
+	return output.String(), nil
+}
+
+func (r *Runner) runHTTP(ctx context.Context, hook suite.HookConfig) (string, error) {
+	if hook.URL == "" {
+		return "", fmt.Errorf("hook %s: url is required", hook.Name)
+	}
+
+	method := hook.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, hook.URL, strings.NewReader(hook.Body))
+	if err != nil {
+		return "", fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+	output := fmt.Sprintf("%s %s: %s\n%s", method, hook.URL, resp.Status, body)
+
+	if hook.ExpectStatus != 0 && resp.StatusCode != hook.ExpectStatus {
+		return output, fmt.Errorf("hook %s: %s %s returned %d, expected %d", hook.Name, method, hook.URL, resp.StatusCode, hook.ExpectStatus)
+	}
+	if hook.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return output, fmt.Errorf("hook %s: %s %s returned %d", hook.Name, method, hook.URL, resp.StatusCode)
+	}
+	return output, nil
+}
+
+// Filter returns the hooks configured to run at the given point
+func Filter(hooks []suite.HookConfig, when string) []suite.HookConfig {
+	var filtered []suite.HookConfig
+	for _, hook := range hooks {
+		if hook.When == when {
+			filtered = append(filtered, hook)
+		}
+	}
+	return filtered
+}
+
+func truncate(output string) string {
+	if len(output) <= maxOutput {
+		return output
+	}
+	return output[:maxOutput] + "\n... output truncated"
+}
@@ -0,0 +1,115 @@
+package hooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"qtm/pkg/suite"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCommandHook(t *testing.T) {
+	runner := NewRunner(nil, "")
+
+	result := runner.Run(context.Background(), suite.HookConfig{Name: "echo", Type: suite.HookCommand, Command: []string{"echo", "migrated"}})
+	if result.Err != nil {
+		t.Fatalf("Run() error = %v", result.Err)
+	}
+	if strings.TrimSpace(result.Output) != "migrated" {
+		t.Errorf("Output = %q, want %q", result.Output, "migrated")
+	}
+
+	result = runner.Run(context.Background(), suite.HookConfig{Name: "fail", Type: suite.HookCommand, Command: []string{"sh", "-c", "echo broken; exit 3"}})
+	if result.Err == nil {
+		t.Errorf("Expected a failing command to return an error")
+	}
+	if !strings.Contains(result.Output, "broken") {
+		t.Errorf("Expected output of a failing command to be captured, got %q", result.Output)
+	}
+}
+
+func TestHTTPHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != "run" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("smoke tests passed"))
+	}))
+	defer server.Close()
+
+	runner := NewRunner(nil, "")
+
+	result := runner.Run(context.Background(), suite.HookConfig{Name: "smoke", Type: suite.HookHTTP, URL: server.URL, Method: http.MethodPost, Body: "run"})
+	if result.Err != nil {
+		t.Fatalf("Run() error = %v", result.Err)
+	}
+	if !strings.Contains(result.Output, "smoke tests passed") {
+		t.Errorf("Expected response body in output, got %q", result.Output)
+	}
+
+	result = runner.Run(context.Background(), suite.HookConfig{Name: "smoke", Type: suite.HookHTTP, URL: server.URL})
+	if result.Err == nil {
+		t.Errorf("Expected a 400 response to fail the hook")
+	}
+}
+
+func TestJobHook(t *testing.T) {
+	kube := fake.NewSimpleClientset()
+	runner := NewRunner(kube, "jobs")
+	runner.JobPollInterval = 5 * time.Millisecond
+
+	// Complete the job once it has been created, as the job controller would
+	go func() {
+		for {
+			jobs, _ := kube.BatchV1().Jobs("jobs").List(context.Background(), metav1.ListOptions{})
+			if len(jobs.Items) > 0 {
+				job := jobs.Items[0]
+				kube.CoreV1().Pods("jobs").Create(context.Background(), &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-pod", Namespace: "jobs", Labels: map[string]string{"job-name": job.Name}},
+				}, metav1.CreateOptions{})
+				job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+				kube.BatchV1().Jobs("jobs").UpdateStatus(context.Background(), &job, metav1.UpdateOptions{})
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	manifest := `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: migrate
+          image: migrate:latest
+`
+	result := runner.Run(context.Background(), suite.HookConfig{Name: "migrate", Type: suite.HookJob, Manifest: manifest, Timeout: time.Second})
+	if result.Err != nil {
+		t.Fatalf("Run() error = %v", result.Err)
+	}
+	if !strings.Contains(result.Output, "migrate-") {
+		t.Errorf("Expected pod logs in output, got %q", result.Output)
+	}
+	if jobs, _ := kube.BatchV1().Jobs("jobs").List(context.Background(), metav1.ListOptions{}); len(jobs.Items) != 0 {
+		t.Errorf("Expected the job to be deleted, found %d", len(jobs.Items))
+	}
+
+	if result := NewRunner(nil, "").Run(context.Background(), suite.HookConfig{Name: "migrate", Type: suite.HookJob, Manifest: manifest}); result.Err == nil {
+		t.Errorf("Expected a job hook without a kubernetes client to fail")
+	}
+}
@@ -0,0 +1,155 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qtm/pkg/suite"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// jobTTL is how long Kubernetes keeps a finished hook Job whose manifest sets
+// no TTL, in case qtm exits before deleting it
+const jobTTL int32 = 3600
+
+// runJob creates the Job described by the hook manifest, waits for it to
+// finish, collects the logs of its pods and deletes it
+func (r *Runner) runJob(ctx context.Context, hook suite.HookConfig) (string, error) {
+	if r.Kube == nil {
+		return "", fmt.Errorf("hook %s: no kubernetes client configured", hook.Name)
+	}
+
+	job, err := loadJob(hook)
+	if err != nil {
+		return "", err
+	}
+
+	namespace := hook.Namespace
+	if namespace == "" {
+		namespace = job.Namespace
+	}
+	if namespace == "" {
+		namespace = r.DefaultNamespace
+	}
+
+	// Give every run its own name so hooks can be re-run within a session
+	baseName := job.Name
+	if baseName == "" {
+		baseName = hook.Name
+	}
+	job.Name = fmt.Sprintf("%s-%s", baseName, uuid.New().String()[:8])
+	job.Namespace = namespace
+	if job.Spec.TTLSecondsAfterFinished == nil {
+		ttl := jobTTL
+		job.Spec.TTLSecondsAfterFinished = &ttl
+	}
+
+	created, err := r.Kube.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("hook %s: failed to create job: %w", hook.Name, err)
+	}
+
+	waitErr := r.waitForJob(ctx, namespace, created.Name)
+
+	// Collect logs even when the job failed, they usually explain why
+	logCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	output := r.jobLogs(logCtx, namespace, created.Name)
+
+	// The Job has served its purpose once its logs are collected
+	propagation := metav1.DeletePropagationBackground
+	if err := r.Kube.BatchV1().Jobs(namespace).Delete(logCtx, created.Name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		output += fmt.Sprintf("failed to delete job %s: %v\n", created.Name, err)
+	}
+
+	if waitErr != nil {
+		return output, fmt.Errorf("hook %s: %w", hook.Name, waitErr)
+	}
+	return output, nil
+}
+
+func (r *Runner) waitForJob(ctx context.Context, namespace, name string) error {
+	interval := r.JobPollInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := r.Kube.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return nil
+			case batchv1.JobFailed:
+				return fmt.Errorf("job %s/%s failed: %s", namespace, name, condition.Message)
+			}
+		}
+		if job.Status.Succeeded > 0 && job.Status.Active == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) jobLogs(ctx context.Context, namespace, name string) string {
+	pods, err := r.Kube.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + name})
+	if err != nil {
+		return fmt.Sprintf("failed to list pods of job %s: %v", name, err)
+	}
+
+	var output strings.Builder
+	for _, pod := range pods.Items {
+		logs, err := r.Kube.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+		if err != nil {
+			fmt.Fprintf(&output, "--- %s: failed to fetch logs: %v\n", pod.Name, err)
+			continue
+		}
+		fmt.Fprintf(&output, "--- %s\n%s\n", pod.Name, logs)
+	}
+	return output.String()
+}
+
+// loadJob decodes the Job manifest of a hook from its inline or file source
+func loadJob(hook suite.HookConfig) (*batchv1.Job, error) {
+	manifest := []byte(hook.Manifest)
+	if hook.ManifestFile != "" {
+		data, err := os.ReadFile(hook.ManifestFile)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: %w", hook.Name, err)
+		}
+		manifest = data
+	}
+	if len(manifest) == 0 {
+		return nil, fmt.Errorf("hook %s: manifest or manifestFile is required", hook.Name)
+	}
+
+	var job batchv1.Job
+	if err := yaml.UnmarshalStrict(manifest, &job); err != nil {
+		return nil, fmt.Errorf("hook %s: invalid job manifest: %w", hook.Name, err)
+	}
+	if job.Kind != "" && job.Kind != "Job" {
+		return nil, fmt.Errorf("hook %s: manifest kind is %s, expected Job", hook.Name, job.Kind)
+	}
+	return &job, nil
+}
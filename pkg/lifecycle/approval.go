@@ -0,0 +1,51 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qtm/pkg/session"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrApprovalRejected is returned when a phase approval is rejected
+var ErrApprovalRejected = errors.New("approval rejected")
+
+// awaitApproval records a pending approval for the phase in the session and
+// blocks until it is approved, rejected or the context is cancelled. Sessions
+// are reused across rollouts, so a decision left by an earlier rollout is
+// replaced rather than trusted.
+func awaitApproval(ctx context.Context, sm session.SessionManager, phase int, interval time.Duration, logger *zap.Logger) error {
+	if err := sm.RequestApproval(phase); err != nil {
+		return fmt.Errorf("failed to request approval: %w", err)
+	}
+	logger.Info("Waiting for approval", zap.Int("phase", phase))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		approvals, err := sm.GetApprovals()
+		if err != nil {
+			return fmt.Errorf("failed to read approvals: %w", err)
+		}
+
+		approval := approvals[phase]
+		switch approval.State {
+		case session.ApprovalApproved:
+			logger.Info("Phase approved", zap.Int("phase", phase), zap.String("decidedBy", approval.DecidedBy))
+			return nil
+		case session.ApprovalRejected:
+			logger.Warn("Phase rejected", zap.Int("phase", phase), zap.String("decidedBy", approval.DecidedBy), zap.String("reason", approval.Reason))
+			return fmt.Errorf("phase %d: %w by %s", phase, ErrApprovalRejected, approval.DecidedBy)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package lifecycle
+
+import (
+	"fmt"
+	"qtm/pkg/deployment"
+	"qtm/pkg/rollback"
+	"qtm/pkg/session"
+
+	"go.uber.org/zap"
+)
+
+// CancelPolicy decides what happens to deployed apps when a rollout is cancelled
+type CancelPolicy string
+
+const (
+	CancelRollbackPhase CancelPolicy = "rollback-phase" // Roll back the phase that was in flight
+	CancelRollbackAll   CancelPolicy = "rollback-all"   // Roll back every phase deployed by the rollout
+	CancelLeave         CancelPolicy = "leave"          // Leave everything deployed as it is
+)
+
+// ParseCancelPolicy validates a cancellation policy name
+func ParseCancelPolicy(name string) (CancelPolicy, error) {
+	switch policy := CancelPolicy(name); policy {
+	case CancelRollbackPhase, CancelRollbackAll, CancelLeave:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown cancellation policy %q, expected %s, %s or %s", name, CancelRollbackPhase, CancelRollbackAll, CancelLeave)
+}
+
+// handleCancellation applies the cancellation policy once the rollout context
+// is cancelled. Rollbacks run on the rollback context as the rollout context
+// is already done.
+func handleCancellation(o *options, rollbacker rollback.Rollbacker, phaseInfos map[int]PhaseInfo, phase int, logger *zap.Logger) bool {
+	logger.Warn("Deployment cancelled", zap.Int("phase", phase), zap.String("policy", string(o.cancelPolicy)))
+	o.journal.Record(session.JournalEntry{Event: session.JournalCancel, Phase: phase, Status: string(o.cancelPolicy)})
+
+	var results []rollback.RollbackResult
+	switch o.cancelPolicy {
+	case CancelLeave:
+		logger.Info("Leaving deployed apps in place")
+		return false
+	case CancelRollbackAll:
+		results = rollbackAllPhases(o.rollbackCtx, rollbacker, phaseInfos, phase, logger)
+	default:
+		info := phaseInfos[phase]
+		results = rollbackPhase(o.rollbackCtx, rollbacker, phase, info.SuccessfulApps, logger)
+		info.RollbackStatus = rollback.AggregateStatus(results)
+		phaseInfos[phase] = info
+	}
+
+	o.recordRollback(results)
+	logger.Info("Rollback after cancellation finished", zap.Int("phase", phase), zap.Stringer("rollbackStatus", rollback.AggregateStatus(results)))
+	return false
+}
+
+// recordDeploy adds deployment results to the journal
+func (o *options) recordDeploy(results []deployment.DeploymentResult) {
+	for _, result := range results {
+		o.journal.Record(session.JournalEntry{
+			Event:   session.JournalDeploy,
+			Phase:   result.Phase,
+			App:     result.AppID,
+			Version: result.Version,
+			Status:  result.Status.String(),
+			Message: result.ErrorMsg,
+		})
+	}
+}
+
+// recordRollback adds rollback results to the journal
+func (o *options) recordRollback(results []rollback.RollbackResult) {
+	for _, result := range results {
+		o.journal.Record(session.JournalEntry{
+			Event:   session.JournalRollback,
+			Phase:   result.Phase,
+			App:     result.AppID,
+			Status:  result.Status.String(),
+			Message: result.ErrorMsg,
+		})
+	}
+}
+
+// flushJournal writes pending journal entries to the session
+func (o *options) flushJournal(logger *zap.Logger) {
+	if err := o.journal.Flush(); err != nil {
+		logger.Warn("Failed to flush session journal", zap.Error(err))
+	}
+}
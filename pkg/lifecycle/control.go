@@ -0,0 +1,136 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"qtm/pkg/rollback"
+	"qtm/pkg/session"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrAborted is returned at a boundary once the rollout has been aborted
+	ErrAborted = errors.New("rollout aborted")
+	// ErrAbortedNoRollback is returned at a boundary once the rollout has been aborted without rollback
+	ErrAbortedNoRollback = errors.New("rollout aborted without rollback")
+)
+
+// controller tracks the pause and abort commands sent to a running rollout.
+// Commands take effect at the next app or phase boundary.
+type controller struct {
+	mu      sync.Mutex
+	paused  bool
+	aborted error
+	changed chan struct{}
+}
+
+func newController() *controller {
+	return &controller{changed: make(chan struct{})}
+}
+
+// watch applies the control commands written to the session until ctx is done
+func (c *controller) watch(ctx context.Context, sm session.SessionManager, logger *zap.Logger) {
+	current, commands, err := sm.WatchControl(ctx)
+	if err != nil {
+		logger.Warn("Unable to watch session control, pause and abort are unavailable", zap.Error(err))
+		return
+	}
+
+	// Apply the current command before any boundary is reached
+	if current.IsValid() {
+		logger.Info("Session has a control command set", zap.String("command", string(current)))
+		c.apply(current)
+	}
+
+	go func() {
+		for command := range commands {
+			logger.Info("Received control command", zap.String("command", string(command)))
+			c.apply(command)
+		}
+	}()
+}
+
+func (c *controller) apply(command session.ControlCommand) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// An abort is final, later commands cannot undo it
+	if c.aborted != nil {
+		return
+	}
+
+	switch command {
+	case session.ControlPause:
+		c.paused = true
+	case session.ControlResume:
+		c.paused = false
+	case session.ControlAbort:
+		c.aborted = ErrAborted
+	case session.ControlAbortNoRollback:
+		c.aborted = ErrAbortedNoRollback
+	}
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// checkpoint blocks while the rollout is paused and returns an error once it
+// has been aborted or ctx is done
+func (c *controller) checkpoint(ctx context.Context, logger *zap.Logger) error {
+	logged := false
+	for {
+		c.mu.Lock()
+		paused, aborted, changed := c.paused, c.aborted, c.changed
+		c.mu.Unlock()
+
+		if aborted != nil {
+			return aborted
+		}
+		if !paused {
+			if logged {
+				logger.Info("Rollout resumed")
+			}
+			return nil
+		}
+		if !logged {
+			logger.Info("Rollout paused, waiting for resume")
+			logged = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// abortErr returns the abort error once the rollout has been aborted
+func (c *controller) abortErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.aborted
+}
+
+// abortRollout stops the rollout after an abort command, rolling back every
+// phase deployed so far unless the abort asked to leave them in place
+func abortRollout(ctx context.Context, o *options, rollbacker rollback.Rollbacker, phaseInfos map[int]PhaseInfo, phase int, err error, logger *zap.Logger) bool {
+	policy := CancelLeave
+	if errors.Is(err, ErrAborted) {
+		policy = CancelRollbackAll
+	}
+	o.journal.Record(session.JournalEntry{Event: session.JournalCancel, Phase: phase, Status: string(policy), Message: err.Error()})
+
+	if policy == CancelLeave {
+		logger.Warn("Rollout aborted, leaving deployed apps in place", zap.Int("phase", phase))
+		return false
+	}
+
+	logger.Warn("Rollout aborted, rolling back deployed phases", zap.Int("phase", phase))
+	results := rollbackAllPhases(ctx, rollbacker, phaseInfos, phase, logger)
+	o.recordRollback(results)
+	logger.Info("Rollback finished", zap.Int("phase", phase), zap.Stringer("rollbackStatus", rollback.AggregateStatus(results)))
+	return false
+}
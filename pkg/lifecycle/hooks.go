@@ -0,0 +1,48 @@
+package lifecycle
+
+import (
+	"context"
+	"qtm/pkg/hooks"
+	"qtm/pkg/session"
+	"qtm/pkg/suite"
+
+	"go.uber.org/zap"
+)
+
+// Scopes recorded with hook results in the session
+const (
+	hookScopeSuite = "suite"
+	hookScopePhase = "phase"
+	hookScopeApp   = "app"
+)
+
+// runHooks runs hooks in order, recording each outcome in the session. It
+// stops at the first failing hook and returns its error.
+func runHooks(ctx context.Context, runner *hooks.Runner, sm session.SessionManager, configs []suite.HookConfig, scope, target string, logger *zap.Logger) error {
+	for _, hook := range configs {
+		logger.Info("Running hook", zap.String("hook", hook.Name), zap.String("scope", scope), zap.String("target", target), zap.String("when", hook.When))
+		result := runner.Run(ctx, hook)
+
+		record := session.HookRecord{
+			Name:       hook.Name,
+			Scope:      scope,
+			Target:     target,
+			When:       hook.When,
+			Type:       hook.Type,
+			Output:     result.Output,
+			StartedAt:  result.StartedAt,
+			FinishedAt: result.FinishedAt,
+		}
+		if result.Err != nil {
+			record.Error = result.Err.Error()
+		}
+		if err := sm.AddHookResult(record); err != nil {
+			logger.Warn("Failed to record hook result in session", zap.String("hook", hook.Name), zap.Error(err))
+		}
+
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+	return nil
+}
@@ -2,9 +2,14 @@ package lifecycle
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"qtm/pkg/catalog"
 	"qtm/pkg/deployment"
+	"qtm/pkg/hooks"
 	"qtm/pkg/rollback"
 	"qtm/pkg/suite"
+	"strconv"
 	"sync"
 
 	"github.com/google/uuid"
@@ -12,41 +17,89 @@ import (
 )
 
 type PhaseInfo struct {
-	SuccessfulApps []string // List of app IDs that were successfully deployed in this phase
-	IsSuccessful   bool     // Indicates whether the phase was overall successful
+	SuccessfulApps    []string                      // List of app IDs that were successfully deployed in this phase
+	IsSuccessful      bool                          // Indicates whether the phase was overall successful
+	Results           []deployment.DeploymentResult // Outcome of every app deployed in this phase
+	RollbackStatus    rollback.RollbackStatus       // Outcome of rolling back this phase, if it was attempted
+	VerificationError string                        // Reason the phase failed verification while soaking
+	HookError         string                        // Reason the phase failed because of a hook
 }
 
 // DeployAllPhases is a modified function to handle the deployment of all phases
-func DeployAllPhases(ctx context.Context, deployer deployment.Deployer, rollbacker rollback.Rollbacker, suiteData map[int][]suite.SuiteItem, decisionMaker func(int, bool) bool, rollbackEverything bool, logger *zap.Logger) bool {
+func DeployAllPhases(ctx context.Context, deployer deployment.Deployer, rollbacker rollback.Rollbacker, suiteData map[int][]suite.SuiteItem, decisionMaker func(int, bool) bool, rollbackEverything bool, logger *zap.Logger, opts ...Option) bool {
 	logger.Info("Starting deployment", zap.Bool("rollbackEverything", rollbackEverything))
 
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Read every catalog entry before deploying anything, and deploy from
+	// that snapshot so catalog edits mid-rollout cannot mix versions
+	restoreCatalog, err := snapshotCatalog(deployer, suiteData, logger)
+	if err != nil {
+		logger.Error("Catalog prefetch failed, nothing was deployed", zap.Error(err))
+		return false
+	}
+	defer restoreCatalog()
+
 	phaseInfos := make(map[int]PhaseInfo)
+	defer o.flushJournal(logger)
 
-	for phase, apps := range suiteData {
-		logger.Info("Starting phase", zap.Int("phase", phase), zap.Any("apps", apps))
+	// Follow pause, resume and abort commands written to the session
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	o.controller.watch(watchCtx, deployer.GetSessionManager(), logger)
 
-		results := make(chan deployment.DeploymentResult, len(apps))
+	phases := suite.SortedPhases(suiteData)
+	for i, phase := range phases {
+		apps := suiteData[phase]
 
-		var wg sync.WaitGroup
-		for _, app := range apps {
-			wg.Add(1)
-			go func(app suite.SuiteItem) {
-				defer wg.Done()
-				deployment.DeployApp(ctx, deployer, app, phase, results)
-			}(app)
+		if err := o.controller.checkpoint(ctx, logger); err != nil {
+			logger.Info("Stopping before phase", zap.Int("phase", phase), zap.Error(err))
+			if ctx.Err() != nil {
+				return handleCancellation(o, rollbacker, phaseInfos, phase, logger)
+			}
+			return abortRollout(ctx, o, rollbacker, phaseInfos, phase, err, logger)
 		}
 
-		wg.Wait()
-		close(results)
+		// Hold the phase until a human has confirmed it may start
+		if o.phaseConfigs[phase].RequiresApproval() {
+			if err := awaitApproval(ctx, deployer.GetSessionManager(), phase, o.approvalPollInterval, logger); err != nil {
+				logger.Error("Phase not approved, stopping deployment", zap.Int("phase", phase), zap.Error(err))
+				if ctx.Err() != nil {
+					return handleCancellation(o, rollbacker, phaseInfos, phase, logger)
+				}
+				if errors.Is(err, ErrApprovalRejected) {
+					return abortRollout(ctx, o, rollbacker, phaseInfos, phase, fmt.Errorf("%w: %w", ErrAborted, err), logger)
+				}
+				return false
+			}
+		}
 
-		phaseSuccess, successfulApps := processPhaseResults(results, logger)
-		phaseInfos[phase] = PhaseInfo{SuccessfulApps: successfulApps, IsSuccessful: phaseSuccess}
+		logger.Info("Starting phase", zap.Int("phase", phase), zap.Any("apps", apps))
+
+		// Suite level hooks run alongside the hooks of the first and last phase
+		var suiteBefore, suiteAfter []suite.HookConfig
+		if i == 0 {
+			suiteBefore = hooks.Filter(o.suiteHooks, suite.HookBefore)
+		}
+		if i == len(phases)-1 {
+			suiteAfter = hooks.Filter(o.suiteHooks, suite.HookAfter)
+		}
+
+		info := deployPhase(ctx, deployer, phase, apps, suiteBefore, suiteAfter, o, logger)
+		phaseSuccess, successfulApps := info.IsSuccessful, info.SuccessfulApps
+		phaseInfos[phase] = info
+		o.recordDeploy(info.Results)
+		o.flushJournal(logger)
+
+		if err := o.controller.abortErr(); err != nil {
+			return abortRollout(ctx, o, rollbacker, phaseInfos, phase, err, logger)
+		}
 
 		if ctx.Err() != nil {
-			// Context is canceled - perform rollback
-			rolbackCtx := context.Background()
-			RollbackPhase(rolbackCtx, rollbacker, phase, successfulApps, logger)
-			return false
+			return handleCancellation(o, rollbacker, phaseInfos, phase, logger)
 		}
 
 		logger.Info("Phase ended", zap.Int("phase", phase), zap.Any("overall", phaseInfos), zap.Bool("phaseSuccess", phaseSuccess))
@@ -54,13 +107,19 @@ func DeployAllPhases(ctx context.Context, deployer deployment.Deployer, rollback
 		if !decisionMaker(phase, phaseSuccess) {
 			if !phaseSuccess {
 				logger.Info("Initiating rollback due to phase failure", zap.Int("phase", phase))
+				var results []rollback.RollbackResult
 				if rollbackEverything {
 					logger.Info("Rolling back all phases", zap.Int("phase", phase))
-					RollbackAllPhases(ctx, rollbacker, phaseInfos, phase, logger)
+					results = rollbackAllPhases(ctx, rollbacker, phaseInfos, phase, logger)
+					info = phaseInfos[phase]
 				} else {
 					logger.Info("Rolling back single phase", zap.Int("phase", phase))
-					RollbackPhase(ctx, rollbacker, phase, successfulApps, logger)
+					results = rollbackPhase(ctx, rollbacker, phase, successfulApps, logger)
 				}
+				o.recordRollback(results)
+				info.RollbackStatus = rollback.AggregateStatus(results)
+				phaseInfos[phase] = info
+				logger.Info("Rollback finished", zap.Int("phase", phase), zap.Stringer("rollbackStatus", info.RollbackStatus))
 			}
 			return false
 		}
@@ -69,10 +128,127 @@ func DeployAllPhases(ctx context.Context, deployer deployment.Deployer, rollback
 	return true
 }
 
-// Implement rollbackPhase and rollbackAllPhases functions
-func RollbackPhase(ctx context.Context, rollbacker rollback.Rollbacker, phase int, apps []string, logger *zap.Logger) {
+// deployPhase runs the before hooks of a phase, deploys its apps in parallel,
+// then runs the after hooks and soaks the phase. Any failure along the way
+// marks the phase as unsuccessful.
+func deployPhase(ctx context.Context, deployer deployment.Deployer, phase int, apps []suite.SuiteItem, suiteBefore, suiteAfter []suite.HookConfig, o *options, logger *zap.Logger) PhaseInfo {
+	sm := deployer.GetSessionManager()
+	cfg := o.phaseConfigs[phase]
+	cfg.Phase = phase
+	target := strconv.Itoa(phase)
+
+	// Nothing is deployed when a before hook fails
+	err := runHooks(ctx, o.hookRunner, sm, suiteBefore, hookScopeSuite, o.suiteName, logger)
+	if err == nil {
+		err = runHooks(ctx, o.hookRunner, sm, hooks.Filter(cfg.Hooks, suite.HookBefore), hookScopePhase, target, logger)
+	}
+	if err != nil {
+		logger.Error("Before hook failed, skipping phase", zap.Int("phase", phase), zap.Error(err))
+		info := PhaseInfo{HookError: err.Error()}
+		for _, app := range apps {
+			info.Results = append(info.Results, deployment.DeploymentResult{AppID: app.Name, Phase: phase, Status: deployment.Skipped, ErrorMsg: err.Error()})
+		}
+		return info
+	}
+
+	results := make(chan deployment.DeploymentResult, len(apps))
+	hookErrs := make(chan error, len(apps))
+
+	var wg sync.WaitGroup
+	for _, app := range apps {
+		wg.Add(1)
+		go func(app suite.SuiteItem) {
+			defer wg.Done()
+			results <- deployAppWithHooks(ctx, deployer, app, phase, o, hookErrs, logger)
+		}(app)
+	}
+
+	wg.Wait()
+	close(results)
+	close(hookErrs)
+
+	phaseResults, phaseSuccess, successfulApps := processPhaseResults(results, logger)
+	info := PhaseInfo{SuccessfulApps: successfulApps, IsSuccessful: phaseSuccess, Results: phaseResults}
+
+	// Deployed apps whose after hooks failed stay in the successful list so they are rolled back
+	for err := range hookErrs {
+		info.IsSuccessful = false
+		info.HookError = err.Error()
+	}
+
+	if info.IsSuccessful && ctx.Err() == nil {
+		if err := runHooks(ctx, o.hookRunner, sm, hooks.Filter(cfg.Hooks, suite.HookAfter), hookScopePhase, target, logger); err != nil {
+			logger.Error("After hook failed", zap.Int("phase", phase), zap.Error(err))
+			info.IsSuccessful = false
+			info.HookError = err.Error()
+		}
+	}
+
+	// Let the phase soak and fail it if any verification check does
+	if info.IsSuccessful && ctx.Err() == nil && cfg.NeedsVerification() {
+		if err := soakPhase(ctx, o.checkBuilder, sm, cfg, logger); err != nil {
+			logger.Error("Phase verification failed", zap.Int("phase", phase), zap.Error(err))
+			info.IsSuccessful = false
+			info.VerificationError = err.Error()
+		}
+	}
+
+	if info.IsSuccessful && ctx.Err() == nil {
+		if err := runHooks(ctx, o.hookRunner, sm, suiteAfter, hookScopeSuite, o.suiteName, logger); err != nil {
+			logger.Error("Suite after hook failed", zap.Int("phase", phase), zap.Error(err))
+			info.IsSuccessful = false
+			info.HookError = err.Error()
+		}
+	}
+
+	return info
+}
+
+// deployAppWithHooks deploys a single app surrounded by its own before and after hooks.
+// After hook failures are reported on hookErrs as the app itself was deployed.
+func deployAppWithHooks(ctx context.Context, deployer deployment.Deployer, app suite.SuiteItem, phase int, o *options, hookErrs chan<- error, logger *zap.Logger) deployment.DeploymentResult {
+	sm := deployer.GetSessionManager()
+
+	// Pause and abort take effect before each app starts
+	if err := o.controller.checkpoint(ctx, logger); err != nil {
+		status := deployment.Cancelled
+		if ctx.Err() != nil {
+			status = deployment.StatusFromContext(ctx.Err())
+		}
+		return deployment.DeploymentResult{AppID: app.Name, Phase: phase, Status: status, ErrorMsg: err.Error()}
+	}
+
+	if err := runHooks(ctx, o.hookRunner, sm, hooks.Filter(app.Hooks, suite.HookBefore), hookScopeApp, app.Name, logger); err != nil {
+		return deployment.DeploymentResult{AppID: app.Name, Phase: phase, Status: deployment.Fail, ErrorMsg: err.Error()}
+	}
+
+	appResult := make(chan deployment.DeploymentResult, 1)
+	deployment.DeployApp(ctx, deployer, app, phase, o.appOptions, appResult)
+	result := <-appResult
+
+	if result.Status == deployment.Success {
+		if err := runHooks(ctx, o.hookRunner, sm, hooks.Filter(app.Hooks, suite.HookAfter), hookScopeApp, app.Name, logger); err != nil {
+			hookErrs <- err
+		}
+	}
+	return result
+}
+
+// RollbackPhase rolls back the given apps of a single phase and reports the combined outcome
+func RollbackPhase(ctx context.Context, rollbacker rollback.Rollbacker, phase int, apps []string, logger *zap.Logger) rollback.RollbackStatus {
+	return rollback.AggregateStatus(rollbackPhase(ctx, rollbacker, phase, apps, logger))
+}
+
+// rollbackPhase rolls back the given apps of a single phase in parallel and returns each result
+func rollbackPhase(ctx context.Context, rollbacker rollback.Rollbacker, phase int, apps []string, logger *zap.Logger) []rollback.RollbackResult {
+	if rollbacker == nil {
+		logger.Warn("No rollbacker configured, skipping rollback", zap.Int("phase", phase), zap.Any("apps", apps))
+		return nil
+	}
+
 	logger.Info("Rolling back phase", zap.Int("phase", phase), zap.Any("apps", apps))
 	var wg sync.WaitGroup
+	results := make(chan rollback.RollbackResult, len(apps))
 
 	for _, appID := range apps {
 		wg.Add(1)
@@ -86,63 +262,105 @@ func RollbackPhase(ctx context.Context, rollbacker rollback.Rollbacker, phase in
 			goroutineLogger := logger.With(
 				zap.String("appID", appID),
 				zap.String("goroutineID", goroutineID),
+				zap.Int("phase", phase),
 			)
 			goroutineLogger.Info("Starting rollback goroutine")
-			rollback.RollbackApp(ctx, rollbacker, appID, phase, goroutineLogger)
+			results <- rollback.RollbackApp(ctx, rollbacker, appID, phase, goroutineLogger)
 		}(appID)
 	}
 
 	wg.Wait()
+	close(results)
+
+	return collectRollbackResults(results)
 }
 
 // rollbackAllPhases rolls back all phases up to and including the specified phase
-func RollbackAllPhases(ctx context.Context, rollbacker rollback.Rollbacker, phaseInfos map[int]PhaseInfo, upToPhase int, logger *zap.Logger) {
+func RollbackAllPhases(ctx context.Context, rollbacker rollback.Rollbacker, phaseInfos map[int]PhaseInfo, upToPhase int, logger *zap.Logger) rollback.RollbackStatus {
+	return rollback.AggregateStatus(rollbackAllPhases(ctx, rollbacker, phaseInfos, upToPhase, logger))
+}
+
+// rollbackAllPhases rolls back phases from upToPhase down, recording each phase
+// outcome in phaseInfos, and returns the result of every app
+func rollbackAllPhases(ctx context.Context, rollbacker rollback.Rollbacker, phaseInfos map[int]PhaseInfo, upToPhase int, logger *zap.Logger) []rollback.RollbackResult {
+	if rollbacker == nil {
+		logger.Warn("No rollbacker configured, skipping rollback", zap.Int("upToPhase", upToPhase))
+		return nil
+	}
+
 	logger.Info("Rolling back all phases", zap.Int("upToPhase", upToPhase))
+	var allResults []rollback.RollbackResult
 	for phase := upToPhase; phase >= 0; phase-- {
 		info, exists := phaseInfos[phase]
 		if !exists {
 			continue // Skip if no information about the phase
 		}
-		logger.Info("Rolling back phase", zap.Int("phase", phase), zap.Any("apps", info.SuccessfulApps))
-		var wg sync.WaitGroup
-		for _, appID := range info.SuccessfulApps {
-			wg.Add(1)
-			go func(appID string) {
-				defer wg.Done()
-
-				// Generate a unique ID for this goroutine
-				goroutineID := uuid.New().String()
-
-				// Creating a new logger instance for this specific goroutine
-				goroutineLogger := logger.With(
-					zap.String("appID", appID),
-					zap.String("goroutineID", goroutineID),
-					zap.Int("phase", phase),
-				)
-				goroutineLogger.Info("Starting rollback goroutine")
-				rollback.RollbackApp(ctx, rollbacker, appID, phase, goroutineLogger)
-			}(appID)
-		}
-		wg.Wait()
-		logger.Info("Phase rollback completed", zap.Int("phase", phase))
+
+		phaseResults := rollbackPhase(ctx, rollbacker, phase, info.SuccessfulApps, logger)
+		info.RollbackStatus = rollback.AggregateStatus(phaseResults)
+		phaseInfos[phase] = info
+		allResults = append(allResults, phaseResults...)
+		logger.Info("Phase rollback completed", zap.Int("phase", phase), zap.Stringer("rollbackStatus", info.RollbackStatus))
 	}
 	logger.Info("Rollback of all phases completed", zap.Int("upToPhase", upToPhase))
+	return allResults
+}
+
+// collectRollbackResults drains a closed channel of rollback results
+func collectRollbackResults(results chan rollback.RollbackResult) []rollback.RollbackResult {
+	var collected []rollback.RollbackResult
+	for res := range results {
+		collected = append(collected, res)
+	}
+	return collected
 }
 
-// processPhaseResults processes the results of a deployment phase
-func processPhaseResults(results chan deployment.DeploymentResult, logger *zap.Logger) (bool, []string) {
+// processPhaseResults processes the results of a deployment phase. Apps that were
+// unchanged or skipped do not fail the phase but are not rolled back either.
+func processPhaseResults(results chan deployment.DeploymentResult, logger *zap.Logger) ([]deployment.DeploymentResult, bool, []string) {
 	phaseSuccess := true
 	var successfulApps []string
+	var phaseResults []deployment.DeploymentResult
 
 	for res := range results {
-		if res.Status == deployment.Fail {
-			logger.Error("Deployment failed", zap.String("appID", res.AppID), zap.Int("phase", res.Phase), zap.String("errorMsg", res.ErrorMsg))
+		phaseResults = append(phaseResults, res)
+		switch {
+		case res.Status.IsFailure():
+			logger.Error("Deployment failed", zap.String("appID", res.AppID), zap.Int("phase", res.Phase), zap.Stringer("status", res.Status), zap.String("errorMsg", res.ErrorMsg))
 			phaseSuccess = false
-		} else {
+		case res.Status == deployment.Success:
 			successfulApps = append(successfulApps, res.AppID)
+		default:
+			logger.Info("Deployment not applied", zap.String("appID", res.AppID), zap.Int("phase", res.Phase), zap.Stringer("status", res.Status))
 		}
 	}
-	return phaseSuccess, successfulApps
+	return phaseResults, phaseSuccess, successfulApps
+}
+
+// snapshotCatalog prefetches the catalog entry of every suite item and has
+// the deployer read from the snapshot until the returned restore func puts
+// the original catalog source back, so a reused deployer never deploys from
+// an earlier rollout's snapshot
+func snapshotCatalog(deployer deployment.Deployer, suiteData map[int][]suite.SuiteItem, logger *zap.Logger) (func(), error) {
+	source := deployer.GetCatalogSource()
+	if source == nil {
+		return func() {}, nil
+	}
+
+	var refs []catalog.Ref
+	for _, phase := range suite.SortedPhases(suiteData) {
+		for _, item := range suiteData[phase] {
+			refs = append(refs, catalog.Ref{Name: item.Name, Group: item.Group, Version: item.Version})
+		}
+	}
+
+	snapshot, err := catalog.Prefetch(source, refs)
+	if err != nil {
+		return nil, err
+	}
+	deployer.SetCatalogSource(snapshot)
+	logger.Info("Catalog snapshot taken", zap.Int("entries", len(snapshot.Items())), zap.Time("taken", snapshot.Taken))
+	return func() { deployer.SetCatalogSource(source) }, nil
 }
 
 // defaultDecisionMaker is a default implementation of the decisionMaker function
@@ -151,7 +369,11 @@ func DefaultDecisionMaker(phase int, phaseSuccess bool) bool {
 }
 
 func CreatePhaseInfoFromSuite(s suite.Suite) map[int]PhaseInfo {
-	phaseData := suite.OrganizeSuiteData(s)
+	return CreatePhaseInfo(suite.OrganizeSuiteData(s))
+}
+
+// CreatePhaseInfo creates PhaseInfo from organized suite data
+func CreatePhaseInfo(phaseData map[int][]suite.SuiteItem) map[int]PhaseInfo {
 	phaseInfos := make(map[int]PhaseInfo)
 
 	for phase, items := range phaseData {
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"qtm/pkg/catalog"
+	"qtm/pkg/charts"
 	"qtm/pkg/deployment"
 	"qtm/pkg/rollback"
 	"qtm/pkg/session"
 	"qtm/pkg/suite"
+	"strings"
 	"testing"
 	"time"
 
@@ -391,3 +393,422 @@ func TestCancellationWithRollback(t *testing.T) {
 		}
 	}
 }
+
+// Approval Gate: A phase marked as requiring approval waits for a decision recorded in the session.
+func TestApprovalGate(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		approve       bool
+		earlier       bool // Phase 3 was approved by an earlier rollout in the session
+		expectSuccess bool
+		expectPhase3  bool
+	}{
+		{name: "Approved", approve: true, expectSuccess: true, expectPhase3: true},
+		{name: "Rejected", approve: false, expectSuccess: false, expectPhase3: false},
+		{name: "Earlier Approval Not Reused", approve: false, earlier: true, expectSuccess: false, expectPhase3: false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			deployer, rollbacker, ctx, cancel := setupTest()
+			defer cancel()
+
+			s, err := deployer.GetSuiteSource().FetchSuite()
+			if err != nil {
+				t.Fatalf("Error fetching suite: %v", err)
+			}
+			s.Phases = []suite.PhaseConfig{{Phase: 3, Approval: suite.ApprovalRequired}}
+
+			sessionManager := deployer.GetSessionManager()
+			if scenario.earlier {
+				sessionManager.RequestApproval(3)
+				sessionManager.ResolveApproval(3, true, "earlier rollout")
+			}
+			go func() {
+				for {
+					approvals, _ := sessionManager.GetApprovals()
+					if len(session.PendingApprovals(approvals)) > 0 {
+						sessionManager.ResolveApproval(3, scenario.approve, "test")
+						return
+					}
+					time.Sleep(5 * time.Millisecond)
+				}
+			}()
+
+			journal := session.NewJournal(sessionManager)
+			success := DeployAllPhases(ctx, deployer, rollbacker, suite.OrganizeSuiteData(s), DefaultDecisionMaker, false, logger,
+				WithPhaseConfigs(s.PhaseConfigs()),
+				WithApprovalPollInterval(10*time.Millisecond),
+				WithJournal(journal),
+			)
+			if success != scenario.expectSuccess {
+				t.Errorf("Expected success = %v, got %v", scenario.expectSuccess, success)
+			}
+
+			// A rejection aborts the rollout, rolling back what it deployed
+			stopped, ok := journal.Stopped()
+			if rejected := !scenario.approve; ok != rejected || rejected && !strings.Contains(stopped.Message, ErrApprovalRejected.Error()) {
+				t.Errorf("Expected a cancel entry for the rejection = %v, got %+v", rejected, stopped)
+			}
+			for phase, appID := range map[int]string{1: "app1-phase1", 2: "app1-phase2"} {
+				if rolledBack := rollbacker.IsRolledBack(appID, phase); rolledBack == scenario.approve {
+					t.Errorf("Expected %s rolled back = %v, got %v", appID, !scenario.approve, rolledBack)
+				}
+			}
+
+			_, err = sessionManager.GetAppVersion("app1-phase2")
+			if deployed := err == nil || rollbacker.IsRolledBack("app1-phase2", 2); !deployed {
+				t.Errorf("Expected phase 2 to be deployed before the gate")
+			}
+			_, err = sessionManager.GetAppVersion("app1-phase3")
+			if deployed := err == nil; deployed != scenario.expectPhase3 {
+				t.Errorf("Expected phase 3 deployed = %v, got %v", scenario.expectPhase3, deployed)
+			}
+		})
+	}
+}
+
+// Soak Failure: A failing verification check while a phase soaks fails the phase and rolls it back.
+func TestSoakFailureRollsBackPhase(t *testing.T) {
+	deployer, rollbacker, ctx, cancel := setupTest()
+	defer cancel()
+
+	s, err := deployer.GetSuiteSource().FetchSuite()
+	if err != nil {
+		t.Fatalf("Error fetching suite: %v", err)
+	}
+	s.Phases = []suite.PhaseConfig{{
+		Phase:        2,
+		Soak:         20 * time.Millisecond,
+		SoakInterval: 5 * time.Millisecond,
+		Checks:       []suite.CheckConfig{{Name: "smoke", Type: suite.CheckCommand, Command: []string{"false"}}},
+	}}
+
+	success := DeployAllPhases(ctx, deployer, rollbacker, suite.OrganizeSuiteData(s), DefaultDecisionMaker, false, logger,
+		WithPhaseConfigs(s.PhaseConfigs()),
+	)
+	if success {
+		t.Errorf("Expected deployment to fail verification")
+	}
+
+	for _, appID := range []string{"app1-phase2", "app2-phase2", "app3-phase2"} {
+		if !rollbacker.IsRolledBack(appID, 2) {
+			t.Errorf("Expected rollback of %s in phase 2", appID)
+		}
+	}
+	if rollbacker.IsRolledBack("app1-phase1", 1) {
+		t.Errorf("Did not expect phase 1 to be rolled back")
+	}
+}
+
+// Hooks: A failing phase before hook skips the phase, and hook output is captured in the session.
+func TestHooks(t *testing.T) {
+	deployer, rollbacker, ctx, cancel := setupTest()
+	defer cancel()
+
+	s, err := deployer.GetSuiteSource().FetchSuite()
+	if err != nil {
+		t.Fatalf("Error fetching suite: %v", err)
+	}
+	s.Items[0].Hooks = []suite.HookConfig{{Name: "smoke", When: suite.HookAfter, Type: suite.HookCommand, Command: []string{"echo", "smoke ok"}}}
+	s.Phases = []suite.PhaseConfig{{
+		Phase: 2,
+		Hooks: []suite.HookConfig{{Name: "migrate", When: suite.HookBefore, Type: suite.HookCommand, Command: []string{"false"}}},
+	}}
+
+	success := DeployAllPhases(ctx, deployer, rollbacker, suite.OrganizeSuiteData(s), DefaultDecisionMaker, false, logger,
+		WithPhaseConfigs(s.PhaseConfigs()),
+	)
+	if success {
+		t.Errorf("Expected deployment to stop on the failing before hook")
+	}
+
+	sessionManager := deployer.GetSessionManager()
+	if _, err := sessionManager.GetAppVersion("app1-phase2"); err == nil {
+		t.Errorf("Expected phase 2 not to be deployed")
+	}
+
+	records, err := sessionManager.GetHookResults()
+	if err != nil {
+		t.Fatalf("Error reading hook results: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 hook results, got %d", len(records))
+	}
+	if records[0].Name != "smoke" || records[0].Target != "app1-phase1" || !strings.Contains(records[0].Output, "smoke ok") {
+		t.Errorf("Unexpected app hook record %+v", records[0])
+	}
+	if records[1].Name != "migrate" || records[1].Error == "" {
+		t.Errorf("Unexpected phase hook record %+v", records[1])
+	}
+}
+
+// Pause and Resume: A paused rollout deploys nothing until it is resumed.
+func TestPauseAndResume(t *testing.T) {
+	deployer, rollbacker, ctx, cancel := setupTest()
+	defer cancel()
+
+	s, err := deployer.GetSuiteSource().FetchSuite()
+	if err != nil {
+		t.Fatalf("Error fetching suite: %v", err)
+	}
+
+	sessionManager := deployer.GetSessionManager()
+	sessionManager.SetControl(session.ControlPause)
+
+	done := make(chan bool)
+	go func() {
+		done <- DeployAllPhases(ctx, deployer, rollbacker, suite.OrganizeSuiteData(s), DefaultDecisionMaker, false, logger)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if !sessionManager.IsEmpty() {
+		t.Errorf("Expected nothing to be deployed while paused")
+	}
+
+	sessionManager.SetControl(session.ControlResume)
+	select {
+	case success := <-done:
+		if !success {
+			t.Errorf("Expected deployment to succeed after resume")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Deployment did not resume")
+	}
+}
+
+// Abort: An abort rolls back deployed phases unless asked not to.
+func TestAbort(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		command        session.ControlCommand
+		expectRollback bool
+	}{
+		{name: "Abort", command: session.ControlAbort, expectRollback: true},
+		{name: "Abort Without Rollback", command: session.ControlAbortNoRollback, expectRollback: false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			deployer, rollbacker, ctx, cancel := setupTest()
+			defer cancel()
+
+			s, err := deployer.GetSuiteSource().FetchSuite()
+			if err != nil {
+				t.Fatalf("Error fetching suite: %v", err)
+			}
+			s.Phases = []suite.PhaseConfig{{Phase: 3, Approval: suite.ApprovalRequired}}
+
+			// Abort while phase 3 waits at its gate, then let it through to the next boundary
+			sessionManager := deployer.GetSessionManager()
+			go func() {
+				for {
+					approvals, _ := sessionManager.GetApprovals()
+					if len(session.PendingApprovals(approvals)) > 0 {
+						sessionManager.SetControl(scenario.command)
+						time.Sleep(20 * time.Millisecond)
+						sessionManager.ResolveApproval(3, true, "test")
+						return
+					}
+					time.Sleep(5 * time.Millisecond)
+				}
+			}()
+
+			success := DeployAllPhases(ctx, deployer, rollbacker, suite.OrganizeSuiteData(s), DefaultDecisionMaker, false, logger,
+				WithPhaseConfigs(s.PhaseConfigs()),
+				WithApprovalPollInterval(10*time.Millisecond),
+			)
+			if success {
+				t.Errorf("Expected aborted deployment to fail")
+			}
+
+			if _, err := sessionManager.GetAppVersion("app1-phase3"); err == nil {
+				t.Errorf("Expected phase 3 not to be deployed")
+			}
+			for phase, apps := range map[int][]string{1: {"app1-phase1", "app2-phase1"}, 2: {"app1-phase2"}} {
+				for _, appID := range apps {
+					if rollbacker.IsRolledBack(appID, phase) != scenario.expectRollback {
+						t.Errorf("Expected rollback of %s in phase %d = %v", appID, phase, scenario.expectRollback)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCancelPolicies(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		policy         CancelPolicy
+		expectRollback bool
+	}{
+		{name: "Leave", policy: CancelLeave, expectRollback: false},
+		{name: "Rollback All", policy: CancelRollbackAll, expectRollback: true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			deployer, rollbacker, ctx, cancel := setupTest()
+			defer cancel()
+
+			s, err := deployer.GetSuiteSource().FetchSuite()
+			if err != nil {
+				t.Fatalf("Error fetching suite: %v", err)
+			}
+			s.Phases = []suite.PhaseConfig{{Phase: 3, Approval: suite.ApprovalRequired}}
+
+			// Interrupt the rollout while phase 3 waits at its gate
+			sessionManager := deployer.GetSessionManager()
+			go func() {
+				for {
+					approvals, _ := sessionManager.GetApprovals()
+					if len(session.PendingApprovals(approvals)) > 0 {
+						cancel()
+						return
+					}
+					time.Sleep(5 * time.Millisecond)
+				}
+			}()
+
+			journal := session.NewJournal(sessionManager)
+			success := DeployAllPhases(ctx, deployer, rollbacker, suite.OrganizeSuiteData(s), DefaultDecisionMaker, false, logger,
+				WithPhaseConfigs(s.PhaseConfigs()),
+				WithApprovalPollInterval(10*time.Millisecond),
+				WithCancelPolicy(scenario.policy),
+				WithJournal(journal),
+			)
+			if success {
+				t.Errorf("Expected cancelled deployment to fail")
+			}
+
+			for phase, apps := range map[int][]string{1: {"app1-phase1", "app2-phase1"}, 2: {"app1-phase2"}} {
+				for _, appID := range apps {
+					if rollbacker.IsRolledBack(appID, phase) != scenario.expectRollback {
+						t.Errorf("Expected rollback of %s in phase %d = %v", appID, phase, scenario.expectRollback)
+					}
+				}
+			}
+
+			// Everything recorded must have reached the session
+			mock := sessionManager.(*session.MockSessionManager)
+			if got, want := len(mock.JournalEntries()), len(journal.Entries()); got != want || want == 0 {
+				t.Errorf("Expected %d flushed journal entries, got %d", want, got)
+			}
+
+			deployed := journal.Deployed()
+			if scenario.expectRollback && len(deployed) != 0 {
+				t.Errorf("Expected nothing left deployed, got %v", deployed)
+			}
+			if !scenario.expectRollback && len(deployed) != 6 {
+				t.Errorf("Expected 6 apps left deployed, got %v", deployed)
+			}
+		})
+	}
+}
+
+// rejectingVerifier refuses the charts of the listed apps
+type rejectingVerifier map[string]bool
+
+func (v rejectingVerifier) Verify(ctx context.Context, item catalog.CatalogItem) (charts.Verification, error) {
+	verification := charts.Verification{Chart: item.HelmChart, Digest: "abc123"}
+	if v[item.Name] {
+		return verification, charts.ErrDigestMismatch
+	}
+	verification.DigestVerified = true
+	return verification, nil
+}
+
+func TestChartVerification(t *testing.T) {
+	deployer, _, ctx, cancel := setupTest()
+	defer cancel()
+
+	s, err := deployer.GetSuiteSource().FetchSuite()
+	if err != nil {
+		t.Fatalf("Error fetching suite: %v", err)
+	}
+
+	// Without a rollbacker the verified apps stay in the session
+	success := DeployAllPhases(ctx, deployer, nil, suite.OrganizeSuiteData(s), DefaultDecisionMaker, false, logger,
+		WithChartVerifier(rejectingVerifier{"app2-phase1": true}),
+	)
+	if success {
+		t.Errorf("Expected deployment with a rejected chart to fail")
+	}
+
+	apps, err := deployer.GetSessionManager().GetApps()
+	if err != nil {
+		t.Fatalf("GetApps failed: %v", err)
+	}
+	if _, ok := apps["app2-phase1"]; ok {
+		t.Errorf("Expected the rejected chart not to be deployed")
+	}
+	verification := apps["app1-phase1"].Verification
+	if verification == nil || !verification.DigestVerified || verification.Digest != "abc123" {
+		t.Errorf("Expected the verification to be recorded, got %+v", verification)
+	}
+
+	// The refusal is recorded even though the app never reached the session
+	verifications, err := deployer.GetSessionManager().GetVerifications()
+	if err != nil {
+		t.Fatalf("GetVerifications failed: %v", err)
+	}
+	if rejected, ok := verifications["app2-phase1"]; !ok || rejected.Error == "" || rejected.AppVersion == "" {
+		t.Errorf("Expected the refused verification to be recorded, got %+v", rejected)
+	}
+}
+
+func TestCatalogPrefetch(t *testing.T) {
+	deployer, rollbacker, ctx, cancel := setupTest()
+	defer cancel()
+
+	s, err := deployer.GetSuiteSource().FetchSuite()
+	if err != nil {
+		t.Fatalf("Error fetching suite: %v", err)
+	}
+
+	// A pin no catalog entry satisfies in the last phase stops the rollout
+	// before the first phase is deployed
+	suiteData := suite.OrganizeSuiteData(s)
+	for i, item := range suiteData[3] {
+		if item.Name == "app3-phase3" {
+			suiteData[3][i].Version = "^9"
+		}
+	}
+
+	if DeployAllPhases(ctx, deployer, rollbacker, suiteData, DefaultDecisionMaker, false, logger) {
+		t.Errorf("Expected the rollout to fail on a missing catalog entry")
+	}
+	apps, err := deployer.GetSessionManager().GetApps()
+	if err != nil {
+		t.Fatalf("GetApps failed: %v", err)
+	}
+	if len(apps) != 0 {
+		t.Errorf("Expected nothing to be deployed, got %d apps", len(apps))
+	}
+
+	// Once every entry resolves the rollout deploys from the snapshot
+	for i := range suiteData[3] {
+		suiteData[3][i].Version = ""
+	}
+	source := deployer.GetCatalogSource()
+	var during catalog.CatalogSource
+	decisionMaker := func(phase int, phaseSuccess bool) bool {
+		during = deployer.GetCatalogSource()
+		return phaseSuccess
+	}
+	if !DeployAllPhases(ctx, deployer, rollbacker, suiteData, decisionMaker, false, logger) {
+		t.Errorf("Expected the rollout to succeed")
+	}
+	if _, ok := during.(*catalog.Snapshot); !ok {
+		t.Errorf("Expected the deployer to read from the catalog snapshot, got %T", during)
+	}
+
+	// The snapshot belongs to one rollout, a reused deployer takes a fresh one
+	if deployer.GetCatalogSource() != source {
+		t.Errorf("Expected the original catalog source to be restored, got %T", deployer.GetCatalogSource())
+	}
+	suiteData[3][0].Version = "^9"
+	if DeployAllPhases(ctx, deployer, rollbacker, suiteData, DefaultDecisionMaker, false, logger) {
+		t.Errorf("Expected the second rollout to check the catalog again")
+	}
+}
@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"context"
+	"qtm/pkg/deployment"
+	"qtm/pkg/hooks"
+	"qtm/pkg/session"
+	"qtm/pkg/suite"
+	"qtm/pkg/values"
+	"qtm/pkg/verify"
+	"time"
+)
+
+// Option configures optional behaviour of DeployAllPhases
+type Option func(*options)
+
+type options struct {
+	phaseConfigs         map[int]suite.PhaseConfig
+	approvalPollInterval time.Duration
+	checkBuilder         *verify.Builder
+	hookRunner           *hooks.Runner
+	suiteName            string
+	suiteHooks           []suite.HookConfig
+	controller           *controller
+	cancelPolicy         CancelPolicy
+	rollbackCtx          context.Context
+	journal              *session.Journal
+	appOptions           deployment.AppOptions
+}
+
+func defaultOptions() *options {
+	return &options{
+		phaseConfigs:         make(map[int]suite.PhaseConfig),
+		approvalPollInterval: 5 * time.Second,
+		checkBuilder:         verify.NewBuilder(nil, ""),
+		hookRunner:           hooks.NewRunner(nil, ""),
+		controller:           newController(),
+		cancelPolicy:         CancelRollbackPhase,
+		rollbackCtx:          context.Background(),
+	}
+}
+
+// WithPhaseConfigs supplies the per-phase settings of the suite being deployed
+func WithPhaseConfigs(configs map[int]suite.PhaseConfig) Option {
+	return func(o *options) {
+		o.phaseConfigs = configs
+	}
+}
+
+// WithApprovalPollInterval sets how often the session is checked for an approval decision
+func WithApprovalPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.approvalPollInterval = interval
+	}
+}
+
+// WithCheckBuilder sets the builder used to create the verification checks run while a phase soaks
+func WithCheckBuilder(builder *verify.Builder) Option {
+	return func(o *options) {
+		o.checkBuilder = builder
+	}
+}
+
+// WithHookRunner sets the runner used to execute suite, phase and app hooks
+func WithHookRunner(runner *hooks.Runner) Option {
+	return func(o *options) {
+		o.hookRunner = runner
+	}
+}
+
+// WithSuiteHooks supplies the suite level hooks, run around the first and last phase
+func WithSuiteHooks(name string, suiteHooks []suite.HookConfig) Option {
+	return func(o *options) {
+		o.suiteName = name
+		o.suiteHooks = suiteHooks
+	}
+}
+
+// WithCancelPolicy sets what happens to deployed apps when the rollout is cancelled
+func WithCancelPolicy(policy CancelPolicy) Option {
+	return func(o *options) {
+		o.cancelPolicy = policy
+	}
+}
+
+// WithRollbackContext sets the context rollbacks run on after the rollout is cancelled
+func WithRollbackContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.rollbackCtx = ctx
+	}
+}
+
+// WithJournal records every deployment and rollback outcome in the journal
+func WithJournal(journal *session.Journal) Option {
+	return func(o *options) {
+		o.journal = journal
+	}
+}
+
+// WithValueLayers sets the suite and environment Helm values merged into the
+// values of every app
+func WithValueLayers(layers values.Layers) Option {
+	return func(o *options) {
+		o.appOptions.Values = layers
+	}
+}
+
+// WithChartVerifier checks every chart against its catalog entry before it is deployed
+func WithChartVerifier(verifier deployment.ChartVerifier) Option {
+	return func(o *options) {
+		o.appOptions.Verifier = verifier
+	}
+}
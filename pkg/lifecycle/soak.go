@@ -0,0 +1,32 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"qtm/pkg/session"
+	"qtm/pkg/suite"
+	"qtm/pkg/verify"
+
+	"go.uber.org/zap"
+)
+
+// soakPhase keeps verifying a deployed phase for its configured soak period.
+// A failing check is returned as an error so the phase can be treated as failed.
+func soakPhase(ctx context.Context, builder *verify.Builder, sm session.SessionManager, cfg suite.PhaseConfig, logger *zap.Logger) error {
+	endpoints, err := sm.GetEndpoints()
+	if err != nil {
+		return fmt.Errorf("failed to read session endpoints: %w", err)
+	}
+
+	checks, err := builder.Build(cfg.Checks, endpoints)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Soaking phase", zap.Int("phase", cfg.Phase), zap.Duration("soak", cfg.Soak), zap.Int("checks", len(checks)))
+	if err := verify.Soak(ctx, cfg.Soak, cfg.SoakInterval, checks, logger); err != nil {
+		return err
+	}
+	logger.Info("Phase verified", zap.Int("phase", cfg.Phase))
+	return nil
+}
@@ -0,0 +1,34 @@
+package plan
+
+import (
+	"errors"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// HelmReleases reads deployed versions from Helm's release storage
+type HelmReleases struct {
+	config *action.Configuration
+}
+
+func NewHelmReleases(config *action.Configuration) *HelmReleases {
+	return &HelmReleases{config: config}
+}
+
+// DeployedRelease returns the app and chart versions of the deployed release,
+// nil when the app has no deployed release
+func (h *HelmReleases) DeployedRelease(name string) (*Release, error) {
+	rel, err := h.config.Releases.Deployed(name)
+	if errors.Is(err, driver.ErrNoDeployedReleases) || errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return &Release{}, nil
+	}
+	return &Release{AppVersion: rel.Chart.Metadata.AppVersion, ChartVersion: rel.Chart.Metadata.Version}, nil
+}
@@ -0,0 +1,214 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"qtm/pkg/catalog"
+	"qtm/pkg/deployment"
+	"qtm/pkg/session"
+	"qtm/pkg/suite"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Action is the change a rollout would make to a single app
+type Action string
+
+const (
+	ActionInstall   Action = "install"   // The app is not deployed yet
+	ActionUpgrade   Action = "upgrade"   // The app is deployed at an older version
+	ActionNoop      Action = "no-op"     // The app is already deployed at the desired version
+	ActionDowngrade Action = "downgrade" // The app is deployed at a newer version
+)
+
+// Release holds the versions of an app currently deployed by Helm
+type Release struct {
+	AppVersion   string // App version of the chart, empty when the chart sets none
+	ChartVersion string
+}
+
+// ReleaseSource reports the release of an app currently deployed by Helm
+type ReleaseSource interface {
+	DeployedRelease(name string) (*Release, error) // nil when the app is not deployed
+}
+
+// Step describes what a rollout would do with one app
+type Step struct {
+	App              string `json:"app"`
+	Group            string `json:"group"`
+	Chart            string `json:"chart"`
+	Origin           string `json:"origin,omitempty"` // Catalog layer the desired version was read from
+	Version          string `json:"version"`
+	ChartVersion     string `json:"chartVersion,omitempty"` // Chart version or constraint the catalog asks for
+	SessionVersion   string `json:"sessionVersion,omitempty"`
+	HelmVersion      string `json:"helmVersion,omitempty"`      // App version of the release, its chart version when the chart sets none
+	HelmChartVersion string `json:"helmChartVersion,omitempty"` // Chart version of the release
+	Action           Action `json:"action"`
+}
+
+// Current returns the version the app is currently running, preferring what
+// Helm reports over what the session recorded
+func (s Step) Current() string {
+	if s.HelmVersion != "" {
+		return s.HelmVersion
+	}
+	return s.SessionVersion
+}
+
+// Compared returns the desired and current versions the action is decided
+// on. Catalog items that name a chart version, such as those read from a Helm
+// repository index, are compared with the chart version Helm deployed, as
+// their version need not match the chart's app version.
+func (s Step) Compared() (desired, current string) {
+	if s.ChartVersion != "" && s.HelmChartVersion != "" {
+		return s.ChartVersion, s.HelmChartVersion
+	}
+	return s.Version, s.Current()
+}
+
+// PhasePlan lists the steps of a single phase
+type PhasePlan struct {
+	Phase int    `json:"phase"`
+	Steps []Step `json:"steps"`
+}
+
+// Plan is the ordered list of actions a rollout of a suite would take
+type Plan struct {
+	Suite  string      `json:"suite"`
+	Phases []PhasePlan `json:"phases"`
+}
+
+// Build resolves every item of the suite against the catalog and compares the
+// desired version with what the session and Helm currently have. Either of
+// sessionApps and releases may be nil when that source is not available.
+func Build(s suite.Suite, catalogSource catalog.CatalogSource, sessionApps map[string]session.AppData, releases ReleaseSource) (*Plan, error) {
+	suiteData := suite.OrganizeSuiteData(s)
+	plan := &Plan{Suite: s.Name, Phases: []PhasePlan{}}
+
+	for _, phase := range suite.SortedPhases(suiteData) {
+		items := append([]suite.SuiteItem(nil), suiteData[phase]...)
+		sort.Slice(items, func(a, b int) bool { return items[a].Name < items[b].Name })
+
+		phasePlan := PhasePlan{Phase: phase}
+		for _, item := range items {
+			data, err := catalogSource.FetchData(item.Name, item.Group, item.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch catalog data for %s: %w", item.Name, err)
+			}
+
+			step := Step{App: item.Name, Group: item.Group, Chart: data.HelmChart, Origin: data.Origin, Version: data.Version, ChartVersion: data.ChartVersion}
+			if app, ok := sessionApps[item.Name]; ok && isDeployed(app) {
+				step.SessionVersion = app.Version
+			}
+			if releases != nil {
+				release, err := releases.DeployedRelease(item.Name)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read helm release for %s: %w", item.Name, err)
+				}
+				if release != nil {
+					step.HelmVersion = release.AppVersion
+					if step.HelmVersion == "" {
+						step.HelmVersion = release.ChartVersion
+					}
+					step.HelmChartVersion = release.ChartVersion
+				}
+			}
+			step.Action = decide(step.Compared())
+			phasePlan.Steps = append(phasePlan.Steps, step)
+		}
+		plan.Phases = append(plan.Phases, phasePlan)
+	}
+
+	return plan, nil
+}
+
+// Counts returns the number of steps for each action
+func (p *Plan) Counts() map[Action]int {
+	counts := make(map[Action]int)
+	for _, phase := range p.Phases {
+		for _, step := range phase.Steps {
+			counts[step.Action]++
+		}
+	}
+	return counts
+}
+
+// Render writes the plan as a human readable table
+func (p *Plan) Render(out io.Writer) error {
+	fmt.Fprintf(out, "Plan for suite %s\n", p.Suite)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tAPP\tGROUP\tACTION\tCURRENT\tDESIRED\tCHART\tCATALOG")
+	for _, phase := range p.Phases {
+		for _, step := range phase.Steps {
+			desired, current := step.Compared()
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", phase.Phase, step.App, step.Group, step.Action, orDash(current), desired, step.Chart, orDash(step.Origin))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	counts := p.Counts()
+	_, err := fmt.Fprintf(out, "%d to install, %d to upgrade, %d to downgrade, %d unchanged\n",
+		counts[ActionInstall], counts[ActionUpgrade], counts[ActionDowngrade], counts[ActionNoop])
+	return err
+}
+
+// RenderJSON writes the plan as indented JSON
+func (p *Plan) RenderJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// orDash returns the value, or a dash when it is empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// isDeployed reports whether the session app entry reflects a deployed release
+func isDeployed(app session.AppData) bool {
+	return app.Version != "" && (app.Status == "" || app.Status == deployment.Success.String() || app.Status == deployment.Unchanged.String())
+}
+
+// decide compares the desired and current versions. A desired constraint,
+// such as a catalog chart version, is met by any current version satisfying
+// it. Versions that are not semantic versions are treated as an upgrade
+// whenever they differ.
+func decide(desired, current string) Action {
+	switch {
+	case current == "":
+		return ActionInstall
+	case desired == current:
+		return ActionNoop
+	}
+
+	desiredVersion, err := semver.NewVersion(desired)
+	if err != nil {
+		if constraint, err := semver.NewConstraint(desired); err == nil {
+			if currentVersion, err := semver.NewVersion(current); err == nil && constraint.Check(currentVersion) {
+				return ActionNoop
+			}
+		}
+		return ActionUpgrade
+	}
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return ActionUpgrade
+	}
+
+	switch desiredVersion.Compare(currentVersion) {
+	case 0:
+		return ActionNoop
+	case -1:
+		return ActionDowngrade
+	}
+	return ActionUpgrade
+}
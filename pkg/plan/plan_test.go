@@ -0,0 +1,148 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"qtm/pkg/catalog"
+	"qtm/pkg/session"
+	"qtm/pkg/suite"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func newMemoryReleases(t *testing.T, versions map[string]string) *HelmReleases {
+	t.Helper()
+
+	store := storage.Init(driver.NewMemory())
+	for name, version := range versions {
+		rel := &release.Release{
+			Name:    name,
+			Version: 1,
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{Metadata: &chart.Metadata{Name: name, Version: "0.1.0", AppVersion: version}},
+		}
+		if err := store.Create(rel); err != nil {
+			t.Fatalf("Failed to store release %s: %v", name, err)
+		}
+	}
+	return NewHelmReleases(&action.Configuration{Releases: store})
+}
+
+func TestBuild(t *testing.T) {
+	s, err := suite.NewMockSuiteSource().FetchSuite()
+	if err != nil {
+		t.Fatalf("Error fetching suite: %v", err)
+	}
+
+	releases := newMemoryReleases(t, map[string]string{
+		"app1-phase1": "1.0.0",
+		"app3-phase2": "3.3.3",
+		"app1-phase3": "1.2.0",
+	})
+	sessionApps := map[string]session.AppData{
+		"app2-phase2": {Version: "3.0.0", Status: "Success"},
+		"app3-phase3": {Version: "1.0.0", Status: "Fail"},
+	}
+
+	p, err := Build(s, catalog.NewMockCatalogSource(), sessionApps, releases)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	expected := map[string]Action{
+		"app1-phase1": ActionUpgrade,
+		"app2-phase1": ActionInstall,
+		"app2-phase2": ActionDowngrade,
+		"app3-phase2": ActionNoop,
+		"app1-phase3": ActionDowngrade,
+		"app3-phase3": ActionInstall,
+	}
+	lastPhase := 0
+	for _, phase := range p.Phases {
+		if phase.Phase <= lastPhase {
+			t.Errorf("Phases out of order: %d after %d", phase.Phase, lastPhase)
+		}
+		lastPhase = phase.Phase
+		for _, step := range phase.Steps {
+			if want, ok := expected[step.App]; ok && step.Action != want {
+				t.Errorf("%s: action = %s, want %s", step.App, step.Action, want)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := p.RenderJSON(&buf); err != nil {
+		t.Fatalf("RenderJSON failed: %v", err)
+	}
+	var decoded Plan
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Plan JSON did not decode: %v", err)
+	}
+	if len(decoded.Phases) != len(p.Phases) {
+		t.Errorf("Decoded %d phases, want %d", len(decoded.Phases), len(p.Phases))
+	}
+
+	// Steps name the catalog layer their version was read from
+	layered, err := Build(s, catalog.NewLayeredSource(catalog.Layer{Name: "env:prod", Source: catalog.NewMockCatalogSource()}), nil, nil)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if origin := layered.Phases[0].Steps[0].Origin; origin != "env:prod" {
+		t.Errorf("Origin = %q, want env:prod", origin)
+	}
+	buf.Reset()
+	if err := layered.Render(&buf); err != nil || !bytes.Contains(buf.Bytes(), []byte("env:prod")) {
+		t.Errorf("Render error = %v, output:\n%s", err, buf.String())
+	}
+}
+
+// TestBuildChartVersions tests that catalog items naming a chart version are
+// compared with the chart version of the release, not its app version
+func TestBuildChartVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	if err := os.WriteFile(path, []byte("- {name: app1-phase1, group: test, version: 0.1.0, chartVersion: 0.1.0, helmChart: stable/app1}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	catalogSource, err := catalog.NewFileCatalogSource(path)
+	if err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	s := suite.Suite{Name: "charts", Items: []suite.SuiteItem{{Name: "app1-phase1", Group: "test", RolloutPhase: 1}}}
+	p, err := Build(s, catalogSource, nil, newMemoryReleases(t, map[string]string{"app1-phase1": "5.0.0"}))
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if step := p.Phases[0].Steps[0]; step.Action != ActionNoop {
+		t.Errorf("Action = %s, want %s for the deployed chart version: %+v", step.Action, ActionNoop, step)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		desired, current string
+		want             Action
+	}{
+		{"1.0.0", "", ActionInstall},
+		{"1.0.0", "1.0.0", ActionNoop},
+		{"v1.0.0", "1.0.0", ActionNoop},
+		{"1.10.0", "1.9.0", ActionUpgrade},
+		{"1.9.0", "1.10.0", ActionDowngrade},
+		{"latest", "stable", ActionUpgrade},
+		{"^1.2", "1.4.0", ActionNoop},
+		{"^1.2", "2.0.0", ActionUpgrade},
+	}
+
+	for _, tt := range tests {
+		if got := decide(tt.desired, tt.current); got != tt.want {
+			t.Errorf("decide(%q, %q) = %s, want %s", tt.desired, tt.current, got, tt.want)
+		}
+	}
+}
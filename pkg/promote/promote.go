@@ -0,0 +1,214 @@
+// Package promote copies catalog entries from one environment to the next,
+// for example from staging to prod, and records who promoted what and when.
+package promote
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"qtm/pkg/catalog"
+	"qtm/pkg/deployment"
+	"qtm/pkg/rollback"
+	"qtm/pkg/session"
+	"qtm/pkg/values"
+	"sort"
+	"time"
+)
+
+// Source is the catalog of the environment apps are promoted from
+type Source interface {
+	catalog.CatalogSource
+	catalog.CatalogLister
+}
+
+// Destination is the catalog of the environment apps are promoted to
+type Destination interface {
+	catalog.CatalogSource
+	catalog.CatalogWriter
+}
+
+// Target is an app selected for promotion. An empty version promotes the
+// current catalog entry of the source environment.
+type Target struct {
+	Name    string
+	Group   string
+	Version string
+}
+
+// App records the promotion of a single app
+type App struct {
+	Name            string `yaml:"name"`
+	Group           string `yaml:"group"`
+	Version         string `yaml:"version"`
+	PreviousVersion string `yaml:"previousVersion,omitempty"` // Version in the destination before the promotion, empty for new entries
+	Chart           string `yaml:"chart"`
+	ValuesDigest    string `yaml:"valuesDigest,omitempty"`
+	Unchanged       bool   `yaml:"unchanged,omitempty"` // The destination already held the same entry
+}
+
+// Record describes who promoted which apps between two environments and when
+type Record struct {
+	From    string    `yaml:"from"`
+	To      string    `yaml:"to"`
+	Session string    `yaml:"session,omitempty"` // Session the apps were selected from, if any
+	User    string    `yaml:"user"`
+	Time    time.Time `yaml:"time"`
+	Apps    []App     `yaml:"apps"`
+}
+
+// Change is the entry an app takes in the destination catalog
+type Change struct {
+	App  App
+	Item catalog.CatalogItem
+}
+
+// SelectTargets picks the apps to promote. When session apps are given only
+// apps that were successfully rolled out, and not rolled back, are selected
+// at the version they were rolled out with. Otherwise the named apps, or
+// every app of the group when none are named, are taken from the source.
+func SelectTargets(from Source, group string, apps []string, sessionApps map[string]session.AppData) ([]Target, error) {
+	var targets []Target
+	if sessionApps != nil {
+		for name, app := range sessionApps {
+			if group != "" && app.Item.Group != group {
+				continue
+			}
+			if rolledOut(app) {
+				targets = append(targets, Target{Name: name, Group: app.Item.Group, Version: app.Version})
+			}
+		}
+		sortTargets(targets)
+		if len(apps) == 0 {
+			return targets, nil
+		}
+
+		var named []Target
+		for _, name := range apps {
+			target, err := findTarget(targets, name, group)
+			if err != nil {
+				return nil, fmt.Errorf("%s was not successfully rolled out in the session", name)
+			}
+			named = append(named, target)
+		}
+		return named, nil
+	}
+
+	items, err := from.ListData(group)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		targets = append(targets, Target{Name: item.Name, Group: item.Group})
+	}
+	if len(apps) == 0 {
+		return targets, nil
+	}
+
+	var named []Target
+	for _, name := range apps {
+		target, err := findTarget(targets, name, group)
+		if err != nil {
+			return nil, err
+		}
+		named = append(named, target)
+	}
+	return named, nil
+}
+
+// Plan fetches the entries of the targets from the source and compares them
+// with the destination without changing anything
+func Plan(from catalog.CatalogSource, to catalog.CatalogSource, targets []Target) ([]Change, error) {
+	var changes []Change
+	for _, target := range targets {
+		item, err := from.FetchData(target.Name, target.Group, target.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from the source catalog: %w", target.Name, err)
+		}
+
+		app := App{
+			Name:         item.Name,
+			Group:        item.Group,
+			Version:      item.Version,
+			Chart:        item.HelmChart,
+			ValuesDigest: values.Digest(item.Values),
+		}
+		if existing, err := to.FetchData(target.Name, target.Group, ""); err == nil {
+			app.PreviousVersion = existing.Version
+			app.Unchanged = sameEntry(*existing, *item)
+		}
+		changes = append(changes, Change{App: app, Item: *item})
+	}
+	return changes, nil
+}
+
+// Apply writes the changed entries to the destination. The apps written
+// before any failure are returned alongside the error.
+func Apply(to catalog.CatalogWriter, changes []Change) ([]App, error) {
+	var apps []App
+	for _, change := range changes {
+		if !change.App.Unchanged {
+			if err := to.PutData(change.Item); err != nil {
+				return apps, fmt.Errorf("failed to write %s to the destination catalog: %w", change.App.Name, err)
+			}
+		}
+		apps = append(apps, change.App)
+	}
+	return apps, nil
+}
+
+// CurrentUser returns the name of the user running qtm
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// rolledOut reports whether an app was deployed in its session and is still there
+func rolledOut(app session.AppData) bool {
+	status, err := deployment.ParseDeploymentStatus(app.Status)
+	if err != nil || (status != deployment.Success && status != deployment.Unchanged) {
+		return false
+	}
+	return app.RollbackStatus != rollback.RollbackSuccess.String()
+}
+
+// findTarget looks up a target by name, the name must be unique across
+// groups unless a group is given
+func findTarget(targets []Target, name, group string) (Target, error) {
+	var found []Target
+	for _, target := range targets {
+		if target.Name == name && (group == "" || target.Group == group) {
+			found = append(found, target)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Target{}, fmt.Errorf("no catalog entry found for %s", name)
+	case 1:
+		return found[0], nil
+	}
+	return Target{}, fmt.Errorf("%s exists in several groups, select one with --group", name)
+}
+
+// sameEntry reports whether two catalog items deploy the same thing
+func sameEntry(a, b catalog.CatalogItem) bool {
+	return a.Version == b.Version &&
+		a.HelmChart == b.HelmChart &&
+		a.ChartVersion == b.ChartVersion &&
+		a.ChartDigest == b.ChartDigest &&
+		a.Provenance == b.Provenance &&
+		values.Digest(a.Values) == values.Digest(b.Values)
+}
+
+func sortTargets(targets []Target) {
+	sort.Slice(targets, func(a, b int) bool {
+		if targets[a].Group != targets[b].Group {
+			return targets[a].Group < targets[b].Group
+		}
+		return targets[a].Name < targets[b].Name
+	})
+}
@@ -0,0 +1,78 @@
+package promote
+
+import (
+	"os"
+	"path/filepath"
+	"qtm/pkg/catalog"
+	"qtm/pkg/session"
+	"qtm/pkg/suite"
+	"testing"
+)
+
+func newCatalog(t *testing.T, name string, items []catalog.CatalogItem) *catalog.FileCatalog {
+	path := filepath.Join(t.TempDir(), name+".yaml")
+	if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fc, err := catalog.NewFileCatalogSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if err := fc.PutData(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fc
+}
+
+func TestPromoteSessionApps(t *testing.T) {
+	staging := newCatalog(t, "staging", []catalog.CatalogItem{
+		{Name: "api", Group: "core", Version: "1.2.0", HelmChart: "charts/api"},
+		{Name: "web", Group: "core", Version: "2.0.0", HelmChart: "charts/web"},
+		{Name: "worker", Group: "core", Version: "3.1.0", HelmChart: "charts/worker"},
+	})
+	prod := newCatalog(t, "prod", []catalog.CatalogItem{
+		{Name: "api", Group: "core", Version: "1.1.0", HelmChart: "charts/api"},
+		{Name: "web", Group: "core", Version: "2.0.0", HelmChart: "charts/web"},
+	})
+
+	sessionApps := map[string]session.AppData{
+		"api":    {Version: "1.2.0", Item: suite.SuiteItem{Name: "api", Group: "core"}, Status: "Success"},
+		"web":    {Version: "2.0.0", Item: suite.SuiteItem{Name: "web", Group: "core"}, Status: "Unchanged"},
+		"worker": {Version: "3.1.0", Item: suite.SuiteItem{Name: "worker", Group: "core"}, Status: "Fail"},
+	}
+
+	targets, err := SelectTargets(staging, "", nil, sessionApps)
+	if err != nil {
+		t.Fatalf("SelectTargets failed: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Name != "api" || targets[1].Name != "web" {
+		t.Fatalf("Targets = %+v, want api and web", targets)
+	}
+	if _, err := SelectTargets(staging, "", []string{"worker"}, sessionApps); err == nil {
+		t.Errorf("Expected an error for an app that failed to roll out")
+	}
+
+	changes, err := Plan(staging, prod, targets)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	apps, err := Apply(prod, changes)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if apps[0].PreviousVersion != "1.1.0" || apps[0].Version != "1.2.0" || apps[0].Unchanged {
+		t.Errorf("api = %+v, want an upgrade from 1.1.0 to 1.2.0", apps[0])
+	}
+	if !apps[1].Unchanged {
+		t.Errorf("web = %+v, want unchanged", apps[1])
+	}
+	if item, err := prod.FetchData("api", "core", ""); err != nil || item.Version != "1.2.0" {
+		t.Errorf("prod api = %+v, %v, want version 1.2.0", item, err)
+	}
+	if _, err := prod.FetchData("worker", "core", ""); err == nil {
+		t.Errorf("worker should not have been promoted")
+	}
+}
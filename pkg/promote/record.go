@@ -0,0 +1,42 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// Recorder stores promotion records
+type Recorder interface {
+	RecordPromotion(record Record) error
+}
+
+// EtcdRecorder stores promotion records under <prefix>/promotions/<timestamp>
+type EtcdRecorder struct {
+	Client *clientv3.Client
+	Prefix string
+}
+
+func NewEtcdRecorder(client *clientv3.Client, prefix string) *EtcdRecorder {
+	return &EtcdRecorder{
+		Client: client,
+		Prefix: prefix,
+	}
+}
+
+// RecordPromotion writes a promotion record
+func (er *EtcdRecorder) RecordPromotion(record Record) error {
+	data, err := yaml.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode promotion record: %v", err)
+	}
+
+	key := path.Join(er.Prefix, "promotions", fmt.Sprintf("%020d", record.Time.UnixNano()))
+	if _, err := er.Client.Put(context.Background(), key, string(data)); err != nil {
+		return fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	return nil
+}
@@ -36,7 +36,7 @@ func NewMockRollbacker(logger *zap.Logger) *MockRollbacker {
 func (m *MockRollbacker) Rollback(ctx context.Context, appName string, phase int, logger *zap.Logger) RollbackResult {
 	logger.Info("Performing mock rollback", zap.String("releaseName", appName), zap.Int("phase", phase))
 	if ctx.Err() != nil {
-		return RollbackResult{AppID: appName, Phase: phase, Status: RollbackFail, ErrorMsg: "Rollback cancelled"}
+		return RollbackResult{AppID: appName, Phase: phase, Status: StatusFromContext(ctx.Err()), ErrorMsg: "Rollback cancelled"}
 	}
 
 	if m.sleep > 0 {
@@ -16,14 +16,6 @@ type RollbackResult struct {
 	ErrorMsg string
 }
 
-// RollbackStatus represents the status of a rollback
-type RollbackStatus int
-
-const (
-	RollbackSuccess RollbackStatus = iota
-	RollbackFail
-)
-
 // Rollbacker defines the interface for rolling back deployments
 type Rollbacker interface {
 	Rollback(ctx context.Context, appName string, phase int, logger *zap.Logger) RollbackResult
@@ -35,21 +27,29 @@ type Rollbacker interface {
 }
 
 // RollbackApp performs the rollback of a single app
-func RollbackApp(ctx context.Context, rb Rollbacker, appName string, phase int, logger *zap.Logger) {
+func RollbackApp(ctx context.Context, rb Rollbacker, appName string, phase int, logger *zap.Logger) RollbackResult {
 
-	// Check for cancellation before starting deployment
+	// Check for cancellation before starting the rollback
 	if ctx.Err() != nil {
 		logger.Info("Rollback cancelled", zap.String("releaseName", appName), zap.Int("phase", phase))
+		return RollbackResult{AppID: appName, Phase: phase, Status: StatusFromContext(ctx.Err()), ErrorMsg: ctx.Err().Error()}
 	}
 
 	// Perform the actual rollback as part of this instantiation of the deployer
 	result := rb.Rollback(ctx, appName, phase, logger)
+	if err := RollbackPending.ValidateTransition(result.Status); err != nil {
+		logger.Error("Rollbacker returned an invalid status", zap.String("releaseName", appName), zap.Error(err))
+		result.Status = RollbackFail
+		result.ErrorMsg = err.Error()
+	}
 
+	sessionManager := rb.GetSessionManager()
 	if result.Status == RollbackSuccess {
-		rb.GetSessionManager().RemoveApp(appName)
+		sessionManager.RemoveApp(appName)
 		logger.Info("Removed app from session", zap.String("appID", appName))
 	} else {
-		logger.Error("Rollback failed not removing from session", zap.String("releaseName", appName), zap.Int("phase", phase), zap.Any("status", result.Status))
+		sessionManager.UpdateAppRollbackStatus(appName, result.Status.String())
+		logger.Error("Rollback failed not removing from session", zap.String("releaseName", appName), zap.Int("phase", phase), zap.Stringer("status", result.Status))
 	}
-	return
+	return result
 }
@@ -0,0 +1,135 @@
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// RollbackStatus represents the status of a rollback
+type RollbackStatus int
+
+const (
+	RollbackPending     RollbackStatus = iota
+	RollbackSuccess                    // Every targeted app was rolled back
+	RollbackFail                       // The rollback was attempted and failed
+	RollbackSkipped                    // The rollback was not attempted
+	RollbackCancelled                  // The rollback was interrupted by a cancelled context
+	RollbackTimedOut                   // The rollback did not finish before its deadline
+	PartiallyRolledBack                // Some, but not all, targeted apps were rolled back
+)
+
+var rollbackStatusNames = map[RollbackStatus]string{
+	RollbackPending:     "Pending",
+	RollbackSuccess:     "Success",
+	RollbackFail:        "Fail",
+	RollbackSkipped:     "Skipped",
+	RollbackCancelled:   "Cancelled",
+	RollbackTimedOut:    "TimedOut",
+	PartiallyRolledBack: "PartiallyRolledBack",
+}
+
+// rollbackTransitions lists the statuses each status may move to. Anything
+// short of a full rollback may be retried by moving back to RollbackPending.
+var rollbackTransitions = map[RollbackStatus][]RollbackStatus{
+	RollbackPending:     {RollbackSuccess, RollbackFail, RollbackSkipped, RollbackCancelled, RollbackTimedOut, PartiallyRolledBack},
+	RollbackFail:        {RollbackPending},
+	RollbackCancelled:   {RollbackPending},
+	RollbackTimedOut:    {RollbackPending},
+	PartiallyRolledBack: {RollbackPending},
+}
+
+// String returns the name of the status
+func (s RollbackStatus) String() string {
+	if name, ok := rollbackStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("RollbackStatus(%d)", int(s))
+}
+
+// ParseRollbackStatus converts a status name back into a RollbackStatus
+func ParseRollbackStatus(name string) (RollbackStatus, error) {
+	for status, n := range rollbackStatusNames {
+		if n == name {
+			return status, nil
+		}
+	}
+	return RollbackPending, fmt.Errorf("unknown rollback status %q", name)
+}
+
+// CanTransitionTo reports whether moving from s to next is allowed
+func (s RollbackStatus) CanTransitionTo(next RollbackStatus) bool {
+	for _, allowed := range rollbackTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns an error if moving from s to next is not allowed
+func (s RollbackStatus) ValidateTransition(next RollbackStatus) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("invalid rollback status transition from %s to %s", s, next)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the status as its name
+func (s RollbackStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes the status from its name
+func (s *RollbackStatus) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("rollback status must be a string: %w", err)
+	}
+
+	status, err := ParseRollbackStatus(name)
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// StatusFromContext maps a context error onto the matching rollback status
+func StatusFromContext(err error) RollbackStatus {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return RollbackTimedOut
+	}
+	return RollbackCancelled
+}
+
+// AggregateStatus combines the results of rolling back several apps into a
+// single status for the group.
+func AggregateStatus(results []RollbackResult) RollbackStatus {
+	if len(results) == 0 {
+		return RollbackSkipped
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Status == RollbackSuccess {
+			succeeded++
+		}
+	}
+
+	switch {
+	case succeeded == len(results):
+		return RollbackSuccess
+	case succeeded > 0:
+		return PartiallyRolledBack
+	}
+
+	// Nothing was rolled back, report the first failure cause
+	for _, result := range results {
+		if result.Status != RollbackSkipped {
+			return result.Status
+		}
+	}
+	return RollbackSkipped
+}
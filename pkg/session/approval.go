@@ -0,0 +1,34 @@
+package session
+
+import "time"
+
+// ApprovalState is the state of a manual approval gate
+type ApprovalState string
+
+const (
+	ApprovalPending  ApprovalState = "pending"
+	ApprovalApproved ApprovalState = "approved"
+	ApprovalRejected ApprovalState = "rejected"
+)
+
+// Approval records a request for a human to confirm that a phase may start
+type Approval struct {
+	Phase       int           `json:"phase"`
+	State       ApprovalState `json:"state"`
+	RequestedBy string        `json:"requestedBy"`
+	RequestedAt time.Time     `json:"requestedAt"`
+	DecidedBy   string        `json:"decidedBy,omitempty"`
+	DecidedAt   time.Time     `json:"decidedAt,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+}
+
+// PendingApprovals returns the phases that are still waiting on a decision
+func PendingApprovals(approvals map[int]Approval) []int {
+	var phases []int
+	for phase, approval := range approvals {
+		if approval.State == ApprovalPending {
+			phases = append(phases, phase)
+		}
+	}
+	return phases
+}
@@ -0,0 +1,21 @@
+package session
+
+// ControlCommand is an instruction for a running rollout, written to the
+// session by another process
+type ControlCommand string
+
+const (
+	ControlPause           ControlCommand = "pause"
+	ControlResume          ControlCommand = "resume"
+	ControlAbort           ControlCommand = "abort"
+	ControlAbortNoRollback ControlCommand = "abort-no-rollback"
+)
+
+// IsValid reports whether the command is one the lifecycle understands
+func (c ControlCommand) IsValid() bool {
+	switch c {
+	case ControlPause, ControlResume, ControlAbort, ControlAbortNoRollback:
+		return true
+	}
+	return false
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"qtm/pkg/suite"
+	"qtm/pkg/values"
+	"strings"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -124,38 +126,139 @@ func (e *EtcdSessionManager) ValidateSession() (bool, error) {
 
 // AddApp adds an app to the session.
 func (e *EtcdSessionManager) AddApp(app suite.SuiteItem, version string) error {
+	return e.putApp(AppData{
+		Version: version,
+		Item:    app,
+	})
+}
+
+// RemoveApp removes an app from the session.
+func (e *EtcdSessionManager) RemoveApp(appName string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
 	defer cancel()
 
-	appData := AppData{
-		version: version,
-		obj:     app,
+	_, err := e.etcdClient.Delete(ctx, e.appKey(appName))
+	if err != nil {
+		return err
 	}
 
-	jsonData, err := json.Marshal(appData)
+	return nil
+}
+
+// UpdateAppStatus records the latest deployment status of an app in the session.
+func (e *EtcdSessionManager) UpdateAppStatus(appName, status string) error {
+	app, err := e.getApp(appName)
 	if err != nil {
 		return err
 	}
 
-	_, err = e.etcdClient.Put(ctx, fmt.Sprintf("%s/sessions/%s/apps/%s", e.prefix, e.SessionID, app.Name), string(jsonData))
+	app.Status = status
+	return e.putApp(app)
+}
+
+// UpdateAppRollbackStatus records the latest rollback status of an app in the session.
+func (e *EtcdSessionManager) UpdateAppRollbackStatus(appName, status string) error {
+	app, err := e.getApp(appName)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	app.RollbackStatus = status
+	return e.putApp(app)
 }
 
-// RemoveApp removes an app from the session.
-func (e *EtcdSessionManager) RemoveApp(appName string) error {
+// UpdateAppValues records the merged Helm values an app was deployed with.
+func (e *EtcdSessionManager) UpdateAppValues(appName string, v values.Values) error {
+	app, err := e.getApp(appName)
+	if err != nil {
+		return err
+	}
+
+	app.Values = v
+	app.ValuesDigest = values.Digest(v)
+	return e.putApp(app)
+}
+
+// UpdateAppOrigin records the catalog layer an app was deployed from.
+func (e *EtcdSessionManager) UpdateAppOrigin(appName, origin string) error {
+	app, err := e.getApp(appName)
+	if err != nil {
+		return err
+	}
+
+	app.Origin = origin
+	return e.putApp(app)
+}
+
+// UpdateAppVerification records the outcome of verifying the chart of an app.
+func (e *EtcdSessionManager) UpdateAppVerification(appName string, verification ChartVerification) error {
+	app, err := e.getApp(appName)
+	if err != nil {
+		return err
+	}
+
+	app.Verification = &verification
+	return e.putApp(app)
+}
+
+// RecordVerification records the outcome of the latest chart verification of
+// an app, whether or not the app is in the session or was deployed.
+func (e *EtcdSessionManager) RecordVerification(appName string, verification ChartVerification) error {
 	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
 	defer cancel()
 
-	_, err := e.etcdClient.Delete(ctx, fmt.Sprintf("%s/sessions/%s/apps/%s", e.prefix, e.SessionID, appName))
+	jsonData, err := json.Marshal(verification)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = e.etcdClient.Put(ctx, fmt.Sprintf("%s/sessions/%s/verifications/%s", e.prefix, e.SessionID, appName), string(jsonData))
+	return err
+}
+
+// GetVerifications returns the latest chart verification of every app keyed by name.
+func (e *EtcdSessionManager) GetVerifications() (map[string]ChartVerification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	prefix := fmt.Sprintf("%s/sessions/%s/verifications/", e.prefix, e.SessionID)
+	resp, err := e.etcdClient.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	verifications := make(map[string]ChartVerification)
+	for _, kv := range resp.Kvs {
+		var verification ChartVerification
+		if err := json.Unmarshal(kv.Value, &verification); err != nil {
+			return nil, fmt.Errorf("failed to decode verification %s: %w", kv.Key, err)
+		}
+		verifications[strings.TrimPrefix(string(kv.Key), prefix)] = verification
+	}
+
+	return verifications, nil
+}
+
+// GetApps returns every app recorded in the session keyed by name.
+func (e *EtcdSessionManager) GetApps() (map[string]AppData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	resp, err := e.etcdClient.Get(ctx, e.appKey(""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make(map[string]AppData)
+	for _, kv := range resp.Kvs {
+		var app AppData
+		if err := json.Unmarshal(kv.Value, &app); err != nil {
+			return nil, fmt.Errorf("failed to decode app %s: %w", kv.Key, err)
+		}
+		apps[app.Item.Name] = app
+	}
+
+	return apps, nil
 }
 
 // AddEndpoint adds an endpoint to the session.
@@ -171,6 +274,25 @@ func (e *EtcdSessionManager) AddEndpoint(endpointName, address string) error {
 	return nil
 }
 
+// GetEndpoints returns the endpoints recorded in the session keyed by name.
+func (e *EtcdSessionManager) GetEndpoints() (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	endpointPrefix := fmt.Sprintf("%s/sessions/%s/endpoints/", e.prefix, e.SessionID)
+	resp, err := e.etcdClient.Get(ctx, endpointPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[string]string)
+	for _, kv := range resp.Kvs {
+		endpoints[strings.TrimPrefix(string(kv.Key), endpointPrefix)] = string(kv.Value)
+	}
+
+	return endpoints, nil
+}
+
 // AddConfigAdjustment adds a config adjustment to the session.
 func (e *EtcdSessionManager) AddConfigAdjustment(app, filename, data string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
@@ -196,34 +318,254 @@ func (e *EtcdSessionManager) AddConfigAdjustment(app, filename, data string) err
 	return nil
 }
 
+// AddHookResult records the outcome of a hook in the session.
+func (e *EtcdSessionManager) AddHookResult(record HookRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/sessions/%s/hooks/%s-%s", e.prefix, e.SessionID, record.StartedAt.Format(time.RFC3339Nano), record.Name)
+	_, err = e.etcdClient.Put(ctx, key, string(jsonData))
+	return err
+}
+
+// GetHookResults returns the hook outcomes recorded in the session in the order they ran.
+func (e *EtcdSessionManager) GetHookResults() ([]HookRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	resp, err := e.etcdClient.Get(ctx, fmt.Sprintf("%s/sessions/%s/hooks/", e.prefix, e.SessionID), clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []HookRecord
+	for _, kv := range resp.Kvs {
+		var record HookRecord
+		if err := json.Unmarshal(kv.Value, &record); err != nil {
+			return nil, fmt.Errorf("failed to decode hook result %s: %w", kv.Key, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// AddPartialRun records in the session that only some apps were rolled out or back.
+func (e *EtcdSessionManager) AddPartialRun(run PartialRun) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/sessions/%s/partial/%s", e.prefix, e.SessionID, run.StartedAt.Format(time.RFC3339Nano))
+	_, err = e.etcdClient.Put(ctx, key, string(jsonData))
+	return err
+}
+
+// GetPartialRuns returns the partial runs recorded in the session, oldest first.
+func (e *EtcdSessionManager) GetPartialRuns() ([]PartialRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	resp, err := e.etcdClient.Get(ctx, fmt.Sprintf("%s/sessions/%s/partial/", e.prefix, e.SessionID), clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []PartialRun
+	for _, kv := range resp.Kvs {
+		var run PartialRun
+		if err := json.Unmarshal(kv.Value, &run); err != nil {
+			return nil, fmt.Errorf("failed to decode partial run %s: %w", kv.Key, err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// SetControl writes a control command for the rollout running in the session.
+func (e *EtcdSessionManager) SetControl(command ControlCommand) error {
+	if !command.IsValid() {
+		return fmt.Errorf("unknown control command %q", command)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	_, err := e.etcdClient.Put(ctx, e.controlKey(), string(command))
+	return err
+}
+
+// WatchControl returns the current control command of the session, if any, and
+// streams the commands written after it. The channel is closed when ctx is done.
+func (e *EtcdSessionManager) WatchControl(ctx context.Context) (ControlCommand, <-chan ControlCommand, error) {
+	getCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	resp, err := e.etcdClient.Get(getCtx, e.controlKey())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var current ControlCommand
+	if len(resp.Kvs) > 0 {
+		current = ControlCommand(resp.Kvs[0].Value)
+	}
+
+	commands := make(chan ControlCommand, 1)
+	go func() {
+		defer close(commands)
+
+		watch := e.etcdClient.Watch(ctx, e.controlKey(), clientv3.WithRev(resp.Header.Revision+1))
+		for watchResp := range watch {
+			for _, event := range watchResp.Events {
+				command := ControlCommand(event.Kv.Value)
+				if event.Type != clientv3.EventTypePut || !command.IsValid() {
+					continue
+				}
+				select {
+				case commands <- command:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return current, commands, nil
+}
+
+// AppendJournal writes journal entries to the session in a single transaction.
+func (e *EtcdSessionManager) AppendJournal(entries []JournalEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	ops := make([]clientv3.Op, 0, len(entries))
+	for _, entry := range entries {
+		jsonData, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/sessions/%s/journal/%s/%010d", e.prefix, e.SessionID, entry.Run, entry.Seq)
+		ops = append(ops, clientv3.OpPut(key, string(jsonData)))
+	}
+
+	_, err := e.etcdClient.Txn(ctx).Then(ops...).Commit()
+	return err
+}
+
 // IsEmpty checks if the session is empty.
 func (e *EtcdSessionManager) IsEmpty() bool {
 	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
 	defer cancel()
 
-	resp, err := e.etcdClient.Get(ctx, fmt.Sprintf("%s/sessions/%s/apps", e.prefix, e.SessionID))
+	resp, err := e.etcdClient.Get(ctx, e.appKey(""), clientv3.WithPrefix(), clientv3.WithCountOnly())
 	if err != nil {
 		return true
 	}
 
-	return len(resp.Kvs) == 0
+	return resp.Count == 0
 }
 
 // GetAppVersion returns the version of the app.
 func (e *EtcdSessionManager) GetAppVersion(appName string) (string, error) {
+	app, err := e.getApp(appName)
+	if err != nil {
+		return "", err
+	}
+
+	return app.Version, nil
+}
+
+// RequestApproval records a pending approval for the phase in the session.
+func (e *EtcdSessionManager) RequestApproval(phase int) error {
+	return e.putApproval(Approval{
+		Phase:       phase,
+		State:       ApprovalPending,
+		RequestedBy: e.username,
+		RequestedAt: time.Now(),
+	})
+}
+
+// ResolveApproval approves or rejects the pending approval for the phase.
+func (e *EtcdSessionManager) ResolveApproval(phase int, approved bool, reason string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
 	defer cancel()
 
-	resp, err := e.etcdClient.Get(ctx, fmt.Sprintf("%s/sessions/%s/apps/%s/version", e.prefix, e.SessionID, appName))
+	key := e.approvalKey(phase)
+	resp, err := e.etcdClient.Get(ctx, key)
 	if err != nil {
-		return "", err
+		return err
 	}
-
 	if len(resp.Kvs) == 0 {
-		return "", fmt.Errorf("no app found for %s", appName)
+		return fmt.Errorf("no approval requested for phase %d", phase)
+	}
+	revision := resp.Kvs[0].ModRevision
+
+	var approval Approval
+	if err := json.Unmarshal(resp.Kvs[0].Value, &approval); err != nil {
+		return err
+	}
+	if approval.State != ApprovalPending {
+		return fmt.Errorf("approval for phase %d is already %s", phase, approval.State)
 	}
 
-	return string(resp.Kvs[0].Value), nil
+	approval.State = ApprovalRejected
+	if approved {
+		approval.State = ApprovalApproved
+	}
+	approval.DecidedBy = e.username
+	approval.DecidedAt = time.Now()
+	approval.Reason = reason
+
+	jsonData, err := json.Marshal(approval)
+	if err != nil {
+		return err
+	}
+
+	// Only one decision wins when several are made at once
+	txnResp, err := e.etcdClient.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", revision)).
+		Then(clientv3.OpPut(key, string(jsonData))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnResp.Succeeded {
+		return fmt.Errorf("approval for phase %d changed while deciding, check its state and try again", phase)
+	}
+	return nil
+}
+
+// GetApprovals returns every approval recorded in the session keyed by phase.
+func (e *EtcdSessionManager) GetApprovals() (map[int]Approval, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	resp, err := e.etcdClient.Get(ctx, fmt.Sprintf("%s/sessions/%s/approvals/", e.prefix, e.SessionID), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	approvals := make(map[int]Approval)
+	for _, kv := range resp.Kvs {
+		var approval Approval
+		if err := json.Unmarshal(kv.Value, &approval); err != nil {
+			return nil, fmt.Errorf("failed to decode approval %s: %w", kv.Key, err)
+		}
+		approvals[approval.Phase] = approval
+	}
+
+	return approvals, nil
 }
 
 func (e *EtcdSessionManager) CreateSessionID() (string, error) {
@@ -264,3 +606,62 @@ func (e *EtcdSessionManager) updateSessionList(sessionID string, add bool) error
 	_, err = e.etcdClient.Put(ctx, sessionListKey, string(updatedList))
 	return err
 }
+
+func (e *EtcdSessionManager) appKey(appName string) string {
+	return fmt.Sprintf("%s/sessions/%s/apps/%s", e.prefix, e.SessionID, appName)
+}
+
+func (e *EtcdSessionManager) getApp(appName string) (AppData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	resp, err := e.etcdClient.Get(ctx, e.appKey(appName))
+	if err != nil {
+		return AppData{}, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return AppData{}, fmt.Errorf("no app found for %s", appName)
+	}
+
+	var app AppData
+	if err := json.Unmarshal(resp.Kvs[0].Value, &app); err != nil {
+		return AppData{}, err
+	}
+
+	return app, nil
+}
+
+func (e *EtcdSessionManager) putApp(app AppData) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(app)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.etcdClient.Put(ctx, e.appKey(app.Item.Name), string(jsonData))
+	return err
+}
+
+func (e *EtcdSessionManager) controlKey() string {
+	return fmt.Sprintf("%s/sessions/%s/control", e.prefix, e.SessionID)
+}
+
+func (e *EtcdSessionManager) approvalKey(phase int) string {
+	return fmt.Sprintf("%s/sessions/%s/approvals/%d", e.prefix, e.SessionID, phase)
+}
+
+func (e *EtcdSessionManager) putApproval(approval Approval) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(approval)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.etcdClient.Put(ctx, e.approvalKey(approval.Phase), string(jsonData))
+	return err
+}
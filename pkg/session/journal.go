@@ -0,0 +1,128 @@
+package session
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Journal events
+const (
+	JournalDeploy   = "deploy"
+	JournalRollback = "rollback"
+	JournalCancel   = "cancel"
+)
+
+// JournalEntry is a single event recorded while a rollout runs
+type JournalEntry struct {
+	Run     string    `json:"run"` // Rollout that recorded the entry, sessions are reused across rollouts
+	Seq     int       `json:"seq"`
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Phase   int       `json:"phase"`
+	App     string    `json:"app,omitempty"`
+	Version string    `json:"version,omitempty"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Journal buffers the events of a rollout in memory and writes them to the
+// session when flushed, so the record survives even when the process is
+// forced to quit. A nil Journal discards everything recorded to it.
+type Journal struct {
+	mu      sync.Mutex
+	sm      SessionManager
+	run     string
+	entries []JournalEntry
+	flushed int
+}
+
+// NewJournal creates a journal that flushes to the given session manager.
+// Every journal numbers its entries under its own run ID, ordered by the
+// time it was created.
+func NewJournal(sm SessionManager) *Journal {
+	return &Journal{sm: sm, run: time.Now().UTC().Format("20060102T150405.000000000Z")}
+}
+
+// Record appends an event to the journal
+func (j *Journal) Record(entry JournalEntry) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry.Run = j.run
+	entry.Seq = len(j.entries)
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	j.entries = append(j.entries, entry)
+}
+
+// Flush writes every entry not yet written to the session
+func (j *Journal) Flush() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	pending := j.entries[j.flushed:]
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := j.sm.AppendJournal(pending); err != nil {
+		return err
+	}
+	j.flushed = len(j.entries)
+	return nil
+}
+
+// Entries returns a copy of every entry recorded so far
+func (j *Journal) Entries() []JournalEntry {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]JournalEntry(nil), j.entries...)
+}
+
+// Stopped returns the entry recorded when the rollout was cancelled or
+// aborted, false when it was not
+func (j *Journal) Stopped() (JournalEntry, bool) {
+	entries := j.Entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Event == JournalCancel {
+			return entries[i], true
+		}
+	}
+	return JournalEntry{}, false
+}
+
+// Deployed returns the last successful deployment of every app that has not
+// since been rolled back, sorted by phase and app name
+func (j *Journal) Deployed() []JournalEntry {
+	deployed := make(map[string]JournalEntry)
+	for _, entry := range j.Entries() {
+		switch {
+		case entry.Event == JournalDeploy && entry.Status == "Success":
+			deployed[entry.App] = entry
+		case entry.Event == JournalRollback && entry.Status == "Success":
+			delete(deployed, entry.App)
+		}
+	}
+
+	entries := make([]JournalEntry, 0, len(deployed))
+	for _, entry := range deployed {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		if entries[a].Phase != entries[b].Phase {
+			return entries[a].Phase < entries[b].Phase
+		}
+		return entries[a].App < entries[b].App
+	})
+	return entries
+}
@@ -1,27 +1,40 @@
 package session
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"qtm/pkg/suite"
+	"qtm/pkg/values"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type MockSessionManager struct {
-	sessionID string
-	apps      map[string]AppData
-	endpoints map[string]string
-	mu        sync.Mutex
-	logger    *zap.Logger
+	sessionID     string
+	apps          map[string]AppData
+	endpoints     map[string]string
+	configChanges []ConfigChange
+	approvals     map[int]Approval
+	hookResults   []HookRecord
+	control       ControlCommand
+	watchers      []chan ControlCommand
+	journal       []JournalEntry
+	partialRuns   []PartialRun
+	verifications map[string]ChartVerification
+	mu            sync.Mutex
+	logger        *zap.Logger
 }
 
 func NewMockSessionManager(l *zap.Logger) *MockSessionManager {
 	return &MockSessionManager{
-		apps:      make(map[string]AppData),
-		endpoints: make(map[string]string),
-		logger:    l,
+		apps:          make(map[string]AppData),
+		endpoints:     make(map[string]string),
+		approvals:     make(map[int]Approval),
+		verifications: make(map[string]ChartVerification),
+		logger:        l,
 	}
 }
 
@@ -40,6 +53,12 @@ func (m *MockSessionManager) GetSessionID() string {
 	return m.sessionID
 }
 
+func (m *MockSessionManager) RegisterNewSession(sessionID string) error {
+	m.logger.Info("Registering new session", zap.String("sessionID", sessionID))
+	m.sessionID = sessionID
+	return nil
+}
+
 func (m *MockSessionManager) GetSessions() ([]string, error) {
 	return []string{"mock-session-id"}, nil
 }
@@ -83,29 +102,119 @@ func (m *MockSessionManager) RemoveSession() error {
 
 	m.apps = make(map[string]AppData)
 	m.endpoints = make(map[string]string)
+	m.configChanges = nil
+	m.approvals = make(map[int]Approval)
+	m.hookResults = nil
 	return nil
 }
 
-func (m *MockSessionManager) UpdateAppDeploymentStatus(appName string, isDeployed bool) error {
+func (m *MockSessionManager) UpdateAppStatus(appName, status string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.apps[appName]; !exists {
+	app, exists := m.apps[appName]
+	if !exists {
+		return fmt.Errorf("app %s does not exist in the session", appName)
+	}
+
+	app.Status = status
+	m.apps[appName] = app
+	return nil
+}
+
+func (m *MockSessionManager) UpdateAppRollbackStatus(appName, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	app, exists := m.apps[appName]
+	if !exists {
+		return fmt.Errorf("app %s does not exist in the session", appName)
+	}
+
+	app.RollbackStatus = status
+	m.apps[appName] = app
+	return nil
+}
+
+func (m *MockSessionManager) UpdateAppValues(appName string, v values.Values) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	app, exists := m.apps[appName]
+	if !exists {
+		return fmt.Errorf("app %s does not exist in the session", appName)
+	}
+
+	app.Values = v
+	app.ValuesDigest = values.Digest(v)
+	m.apps[appName] = app
+	return nil
+}
+
+func (m *MockSessionManager) UpdateAppOrigin(appName, origin string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	app, exists := m.apps[appName]
+	if !exists {
 		return fmt.Errorf("app %s does not exist in the session", appName)
 	}
 
-	app := m.apps[appName]
+	app.Origin = origin
 	m.apps[appName] = app
+	return nil
+}
+
+func (m *MockSessionManager) UpdateAppVerification(appName string, verification ChartVerification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	app, exists := m.apps[appName]
+	if !exists {
+		return fmt.Errorf("app %s does not exist in the session", appName)
+	}
+
+	app.Verification = &verification
+	m.apps[appName] = app
+	return nil
+}
 
+func (m *MockSessionManager) RecordVerification(appName string, verification ChartVerification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.verifications[appName] = verification
 	return nil
 }
 
+func (m *MockSessionManager) GetVerifications() (map[string]ChartVerification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	verifications := make(map[string]ChartVerification, len(m.verifications))
+	for name, verification := range m.verifications {
+		verifications[name] = verification
+	}
+	return verifications, nil
+}
+
+func (m *MockSessionManager) GetApps() (map[string]AppData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	apps := make(map[string]AppData, len(m.apps))
+	for name, app := range m.apps {
+		apps[name] = app
+	}
+	return apps, nil
+}
+
 func (m *MockSessionManager) LocateSession(sessionID string) (SessionData, error) {
 	return SessionData{
 		SessionID:     m.sessionID,
 		Apps:          m.apps,
 		Endpoints:     m.endpoints,
-		ConfigChanges: make([]ConfigChange, 0),
+		ConfigChanges: m.configChanges,
 	}, nil
 }
 
@@ -114,9 +223,16 @@ func (m *MockSessionManager) GetData() (SessionData, error) {
 	return m.LocateSession(m.sessionID)
 }
 
-func (m *MockSessionManager) GetEndpoints(sessionID string) (map[string]string, error) {
-	m.logger.Info("Getting session endpoints", zap.String("sessionID", sessionID))
-	return m.endpoints, nil
+func (m *MockSessionManager) GetEndpoints() (map[string]string, error) {
+	m.logger.Info("Getting session endpoints", zap.String("sessionID", m.sessionID))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	endpoints := make(map[string]string, len(m.endpoints))
+	for name, address := range m.endpoints {
+		endpoints[name] = address
+	}
+	return endpoints, nil
 }
 
 func (m *MockSessionManager) AddApp(app suite.SuiteItem, version string) error {
@@ -124,34 +240,32 @@ func (m *MockSessionManager) AddApp(app suite.SuiteItem, version string) error {
 	defer m.mu.Unlock()
 
 	m.apps[app.Name] = AppData{
-		version: version,
-		obj:     app,
+		Version: version,
+		Item:    app,
 	}
 
 	return nil
 }
 
-func (m *MockSessionManager) AddEndpoint(sessionID, endpointName, address string) error {
-	m.logger.Info("Adding endpoint", zap.String("sessionID", sessionID), zap.String("endpointName", endpointName), zap.String("address", address))
+func (m *MockSessionManager) AddEndpoint(endpointName, address string) error {
+	m.logger.Info("Adding endpoint", zap.String("sessionID", m.sessionID), zap.String("endpointName", endpointName), zap.String("address", address))
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.endpoints[endpointName] = address
 	return nil
 }
 
-func (m *MockSessionManager) AddConfigAdjustment(sessionID, app, filename, data string) error {
-	//m.logger.Info("Adding config adjustment", zap.String("sessionID", sessionID), zap.String("app", app), zap.String("filename", filename), zap.String("data", data))
-	//if session, exists := m.sessions[sessionID]; exists {
-	//	session.ConfigChanges = append(session.ConfigChanges, ConfigChange{
-	//		App:       app,
-	//		Filename:  filename,
-	//		Data:      data,
-	//		Timestamp: time.Now().Format(time.RFC3339),
-	//	})
-	//	m.sessions[sessionID] = session
-	//	return nil
-	//}
-	return errors.New("session not found")
+func (m *MockSessionManager) AddConfigAdjustment(app, filename, data string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.configChanges = append(m.configChanges, ConfigChange{
+		App:       app,
+		Filename:  filename,
+		Data:      data,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	return nil
 }
 
 func (m *MockSessionManager) GetAppVersion(appName string) (string, error) {
@@ -159,11 +273,145 @@ func (m *MockSessionManager) GetAppVersion(appName string) (string, error) {
 	defer m.mu.Unlock()
 
 	if app, exists := m.apps[appName]; exists {
-		return app.version, nil
+		return app.Version, nil
 	}
 	return "", errors.New("app not found")
 }
 
+func (m *MockSessionManager) RequestApproval(phase int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.approvals[phase] = Approval{
+		Phase:       phase,
+		State:       ApprovalPending,
+		RequestedBy: "mock",
+		RequestedAt: time.Now(),
+	}
+	return nil
+}
+
+func (m *MockSessionManager) ResolveApproval(phase int, approved bool, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	approval, exists := m.approvals[phase]
+	if !exists || approval.State != ApprovalPending {
+		return fmt.Errorf("no pending approval for phase %d", phase)
+	}
+
+	approval.State = ApprovalRejected
+	if approved {
+		approval.State = ApprovalApproved
+	}
+	approval.DecidedBy = "mock"
+	approval.DecidedAt = time.Now()
+	approval.Reason = reason
+	m.approvals[phase] = approval
+	return nil
+}
+
+func (m *MockSessionManager) GetApprovals() (map[int]Approval, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	approvals := make(map[int]Approval, len(m.approvals))
+	for phase, approval := range m.approvals {
+		approvals[phase] = approval
+	}
+	return approvals, nil
+}
+
+func (m *MockSessionManager) AddHookResult(record HookRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hookResults = append(m.hookResults, record)
+	return nil
+}
+
+func (m *MockSessionManager) GetHookResults() ([]HookRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]HookRecord(nil), m.hookResults...), nil
+}
+
+func (m *MockSessionManager) AddPartialRun(run PartialRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.partialRuns = append(m.partialRuns, run)
+	return nil
+}
+
+func (m *MockSessionManager) GetPartialRuns() ([]PartialRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]PartialRun(nil), m.partialRuns...), nil
+}
+
+func (m *MockSessionManager) SetControl(command ControlCommand) error {
+	if !command.IsValid() {
+		return fmt.Errorf("unknown control command %q", command)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.control = command
+	for _, watcher := range m.watchers {
+		select {
+		case watcher <- command:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *MockSessionManager) WatchControl(ctx context.Context) (ControlCommand, <-chan ControlCommand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	watcher := make(chan ControlCommand, 16)
+	m.watchers = append(m.watchers, watcher)
+
+	commands := make(chan ControlCommand)
+	go func() {
+		defer close(commands)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case command := <-watcher:
+				select {
+				case commands <- command:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return m.control, commands, nil
+}
+
+func (m *MockSessionManager) AppendJournal(entries []JournalEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.journal = append(m.journal, entries...)
+	return nil
+}
+
+// JournalEntries returns the journal entries flushed to the mock session
+func (m *MockSessionManager) JournalEntries() []JournalEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]JournalEntry(nil), m.journal...)
+}
+
 func (m *MockSessionManager) ValidateSession() (bool, error) {
 	return true, nil
 }
@@ -1,9 +1,12 @@
 package session
 
 import (
+	"context"
 	"fmt"
 	"qtm/internal/prompt"
 	"qtm/pkg/suite"
+	"qtm/pkg/values"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -16,9 +19,31 @@ type SessionData struct {
 	ConfigChanges []ConfigChange
 }
 
+// AppData records an app deployed as part of a session. Status and
+// RollbackStatus hold the names of the last deployment and rollback outcomes.
 type AppData struct {
-	version string
-	obj     suite.SuiteItem
+	Version        string             `json:"version"`
+	Item           suite.SuiteItem    `json:"item"`
+	Status         string             `json:"status,omitempty"`
+	RollbackStatus string             `json:"rollbackStatus,omitempty"`
+	Values         values.Values      `json:"values,omitempty"`       // Merged Helm values the app was deployed with
+	ValuesDigest   string             `json:"valuesDigest,omitempty"` // SHA-256 digest of Values
+	Verification   *ChartVerification `json:"verification,omitempty"` // Verification of the chart of the deployed version
+	Origin         string             `json:"origin,omitempty"`       // Catalog layer the deployed version was read from
+}
+
+// ChartVerification records how the chart of an app was checked before it
+// was deployed
+type ChartVerification struct {
+	AppVersion         string    `json:"appVersion,omitempty"` // Catalog version of the app the chart belongs to
+	Chart              string    `json:"chart"`
+	Version            string    `json:"version,omitempty"`
+	Digest             string    `json:"digest,omitempty"`
+	DigestVerified     bool      `json:"digestVerified"`
+	ProvenanceVerified bool      `json:"provenanceVerified"`
+	SignedBy           []string  `json:"signedBy,omitempty"`
+	Error              string    `json:"error,omitempty"`
+	VerifiedAt         time.Time `json:"verifiedAt"`
 }
 
 type ConfigChange struct {
@@ -28,6 +53,27 @@ type ConfigChange struct {
 	Timestamp string
 }
 
+// HookRecord captures the outcome of a lifecycle hook run during a session
+type HookRecord struct {
+	Name       string    `json:"name"`
+	Scope      string    `json:"scope"`  // suite, phase or app
+	Target     string    `json:"target"` // suite name, phase number or app name
+	When       string    `json:"when"`
+	Type       string    `json:"type"`
+	Output     string    `json:"output"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// PartialRun records a rollout or rollback limited to some of the apps of a suite
+type PartialRun struct {
+	Operation string    `json:"operation"` // rollout or rollback
+	Filter    string    `json:"filter"`    // Selector and app names the apps were picked with
+	Apps      []string  `json:"apps"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
 type SessionOptions struct {
 	Session    string
 	NewSession bool
@@ -42,10 +88,29 @@ type SessionManager interface {
 	ValidateSession() (bool, error)
 	AddApp(app suite.SuiteItem, version string) error
 	RemoveApp(appName string) error
+	UpdateAppStatus(appName, status string) error
+	UpdateAppRollbackStatus(appName, status string) error
+	UpdateAppValues(appName string, v values.Values) error
+	UpdateAppVerification(appName string, verification ChartVerification) error
+	UpdateAppOrigin(appName, origin string) error
+	RecordVerification(appName string, verification ChartVerification) error
+	GetVerifications() (map[string]ChartVerification, error)
+	GetApps() (map[string]AppData, error)
 	AddEndpoint(endpointName, address string) error
+	GetEndpoints() (map[string]string, error)
 	AddConfigAdjustment(app, filename, data string) error
 	IsEmpty() bool
 	GetAppVersion(appName string) (string, error)
+	RequestApproval(phase int) error
+	ResolveApproval(phase int, approved bool, reason string) error
+	GetApprovals() (map[int]Approval, error)
+	AddHookResult(record HookRecord) error
+	GetHookResults() ([]HookRecord, error)
+	SetControl(command ControlCommand) error
+	WatchControl(ctx context.Context) (ControlCommand, <-chan ControlCommand, error)
+	AppendJournal(entries []JournalEntry) error
+	AddPartialRun(run PartialRun) error
+	GetPartialRuns() ([]PartialRun, error)
 }
 
 // SessionManagerHolder holds a reference to a SessionManager
@@ -22,13 +22,46 @@ func NewRemoteSuiteSource(client *clientv3.Client, suite, prefix string) *Remote
 }
 
 func (rs *RemoteEtcdSource) FetchSuite() (Suite, error) {
-	key := fmt.Sprintf("%s/suites/%s", rs.Prefix, rs.Suite)
-	resp, err := rs.Client.Get(context.Background(), key)
+	data, err := rs.FetchDocument()
 	if err != nil {
 		return Suite{}, err
 	}
 
 	//Parse the response etcd resp into a suite
-	fmt.Println("resp:", resp)
-	return Suite{}, nil
+	suite, err := ParseSuite(data)
+	if err != nil {
+		return Suite{}, fmt.Errorf("failed to parse suite %s: %w", rs.Suite, err)
+	}
+	if suite.Name == "" {
+		suite.Name = rs.Suite
+	}
+	return ResolveIncludes(suite, "suite:"+rs.Suite, NewIncludeLoader(rs.Client, rs.Prefix))
+}
+
+// FetchDocument returns the suite document as it is stored
+func (rs *RemoteEtcdSource) FetchDocument() ([]byte, error) {
+	key := fmt.Sprintf("%s/suites/%s", rs.Prefix, rs.Suite)
+	resp, err := rs.Client.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("suite %s not found", rs.Suite)
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// FetchOverlayDocument returns the overlay of an environment as it is stored
+func (rs *RemoteEtcdSource) FetchOverlayDocument(env string) ([]byte, error) {
+	key := fmt.Sprintf("%s/suites/%s/overlays/%s", rs.Prefix, rs.Suite, env)
+	resp, err := rs.Client.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("no overlay found at %s", key)
+	}
+	return resp.Kvs[0].Value, nil
 }
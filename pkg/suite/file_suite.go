@@ -1,38 +1,59 @@
 package suite
 
 import (
-	"errors"
+	"fmt"
 	"os"
-
-	"gopkg.in/yaml.v2"
+	"path/filepath"
+	"strings"
 )
 
 type FileSource struct {
 	SuiteName string
 	Filename  string
+	Loader    *IncludeLoader // Reads included suites, only file includes are possible when nil
 	suite     Suite
 }
 
-func NewFileSuiteSource(filePath string) (*FileSource, error) {
+type FileSourceOption func(*FileSource)
+
+// WithIncludeLoader sets the loader included suites are read with
+func WithIncludeLoader(loader *IncludeLoader) FileSourceOption {
+	return func(fds *FileSource) {
+		fds.Loader = loader
+	}
+}
+
+// NewFileSuiteSource reads a suite file. Suites without a name, such as the
+// legacy bare list, are named after the file.
+func NewFileSuiteSource(filePath string, opts ...FileSourceOption) (*FileSource, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	var suite Suite
-	if err := yaml.Unmarshal(data, &suite.Items); err != nil {
-		return nil, err
+	suite, err := ParseSuite(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
 	}
 
-	return &FileSource{
-		suite: suite,
-	}, nil
-
+	fds := &FileSource{
+		Filename: filePath,
+		suite:    suite,
+	}
+	for _, opt := range opts {
+		opt(fds)
+	}
+	return fds, nil
 }
 
+// FetchSuite returns the suite in the file with its includes resolved. When
+// SuiteName is set the file must define that suite.
 func (fds *FileSource) FetchSuite() (Suite, error) {
-	if fds.suite.Name == fds.SuiteName {
-		return fds.suite, nil
+	if fds.SuiteName == "" || fds.suite.Name == fds.SuiteName {
+		return ResolveIncludes(fds.suite, FileOrigin(fds.Filename), fds.Loader)
 	}
-	return Suite{}, errors.New("suite not found")
+	return Suite{}, fmt.Errorf("suite %s not found, %s defines suite %s", fds.SuiteName, fds.Filename, fds.suite.Name)
 }
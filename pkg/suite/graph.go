@@ -0,0 +1,225 @@
+package suite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"qtm/pkg/catalog"
+	"strings"
+)
+
+// Graph formats accepted by WriteGraph
+const (
+	GraphDot     = "dot"
+	GraphMermaid = "mermaid"
+	GraphJSON    = "json"
+)
+
+// Graph is the rollout order of a suite: its items grouped by phase and the
+// edges between them
+type Graph struct {
+	Suite  string       `json:"suite"`
+	Phases []GraphPhase `json:"phases"`
+	Edges  []GraphEdge  `json:"edges"`
+}
+
+type GraphPhase struct {
+	Phase    int         `json:"phase"`
+	Approval bool        `json:"approval,omitempty"` // The phase waits for a manual approval
+	Nodes    []GraphNode `json:"nodes"`
+}
+
+// GraphNode is an item of the suite annotated with its catalog version
+type GraphNode struct {
+	Name       string `json:"name"`
+	Group      string `json:"group,omitempty"`
+	Constraint string `json:"constraint,omitempty"` // Version pinned by the suite
+	Version    string `json:"version,omitempty"`    // Catalog version the item resolves to
+	Error      string `json:"error,omitempty"`      // Why the catalog version could not be resolved
+}
+
+// GraphEdge orders two items. Dependency edges come from dependsOn, phase
+// edges link consecutive phases of a suite without dependencies and name the
+// phases rather than items.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"` // dependency or phase
+}
+
+// BuildGraph builds the graph of a suite. Versions are looked up in the
+// catalog source when one is given.
+func BuildGraph(s Suite, catalogSource catalog.CatalogSource) Graph {
+	graph := Graph{Suite: s.Name}
+	phaseData := OrganizeSuiteData(s)
+	configs := s.PhaseConfigs()
+
+	for _, phase := range SortedPhases(phaseData) {
+		gp := GraphPhase{Phase: phase, Approval: configs[phase].RequiresApproval()}
+		for _, item := range phaseData[phase] {
+			node := GraphNode{Name: item.Name, Group: item.Group, Constraint: item.Version}
+			if catalogSource != nil {
+				data, err := catalogSource.FetchData(item.Name, item.Group, item.Version)
+				if err != nil {
+					node.Error = err.Error()
+				} else {
+					node.Version = data.Version
+				}
+			}
+			gp.Nodes = append(gp.Nodes, node)
+		}
+		graph.Phases = append(graph.Phases, gp)
+	}
+
+	for _, item := range s.Items {
+		for _, dep := range item.DependsOn {
+			graph.Edges = append(graph.Edges, GraphEdge{From: dep, To: item.Name, Kind: "dependency"})
+		}
+	}
+	if len(graph.Edges) == 0 {
+		for i := 1; i < len(graph.Phases); i++ {
+			graph.Edges = append(graph.Edges, GraphEdge{
+				From: phaseID(graph.Phases[i-1].Phase),
+				To:   phaseID(graph.Phases[i].Phase),
+				Kind: "phase",
+			})
+		}
+	}
+	return graph
+}
+
+// WriteGraph writes the graph in one of the dot, mermaid or json formats
+func WriteGraph(w io.Writer, graph Graph, format string) error {
+	switch format {
+	case GraphDot:
+		return writeDot(w, graph)
+	case GraphMermaid:
+		return writeMermaid(w, graph)
+	case GraphJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(graph)
+	default:
+		return fmt.Errorf("unknown graph format %q, expected dot, mermaid or json", format)
+	}
+}
+
+func writeDot(w io.Writer, graph Graph) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", graph.Suite)
+	b.WriteString("  rankdir=LR;\n  compound=true;\n  node [shape=box];\n")
+
+	// Phase edges are drawn between clusters through their first nodes
+	first := make(map[string]string)
+	for _, gp := range graph.Phases {
+		fmt.Fprintf(&b, "  subgraph \"cluster_%s\" {\n", phaseID(gp.Phase))
+		fmt.Fprintf(&b, "    label=%q;\n", phaseLabel(gp))
+		for _, node := range gp.Nodes {
+			fmt.Fprintf(&b, "    %q [label=%q];\n", nodeID(gp.Phase, node), strings.Join(nodeLabel(node), "\n"))
+		}
+		b.WriteString("  }\n")
+		if len(gp.Nodes) > 0 {
+			first[phaseID(gp.Phase)] = nodeID(gp.Phase, gp.Nodes[0])
+		}
+	}
+
+	ids := graphNodeIDs(graph)
+	for _, edge := range graph.Edges {
+		if edge.Kind == "phase" {
+			fmt.Fprintf(&b, "  %q -> %q [ltail=%q, lhead=%q];\n", first[edge.From], first[edge.To], "cluster_"+edge.From, "cluster_"+edge.To)
+			continue
+		}
+		for _, from := range ids[edge.From] {
+			for _, to := range ids[edge.To] {
+				fmt.Fprintf(&b, "  %q -> %q;\n", from, to)
+			}
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMermaid(w io.Writer, graph Graph) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, gp := range graph.Phases {
+		fmt.Fprintf(&b, "  subgraph %s[\"%s\"]\n", phaseID(gp.Phase), mermaidEscape(phaseLabel(gp)))
+		for _, node := range gp.Nodes {
+			fmt.Fprintf(&b, "    %s[\"%s\"]\n", nodeID(gp.Phase, node), mermaidEscape(strings.Join(nodeLabel(node), "<br/>")))
+		}
+		b.WriteString("  end\n")
+	}
+
+	ids := graphNodeIDs(graph)
+	for _, edge := range graph.Edges {
+		if edge.Kind == "phase" {
+			fmt.Fprintf(&b, "  %s --> %s\n", edge.From, edge.To)
+			continue
+		}
+		for _, from := range ids[edge.From] {
+			for _, to := range ids[edge.To] {
+				fmt.Fprintf(&b, "  %s --> %s\n", from, to)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// graphNodeIDs maps app names to the ids of their nodes, an app may appear
+// in several phases
+func graphNodeIDs(graph Graph) map[string][]string {
+	ids := make(map[string][]string)
+	for _, gp := range graph.Phases {
+		for _, node := range gp.Nodes {
+			ids[node.Name] = append(ids[node.Name], nodeID(gp.Phase, node))
+		}
+	}
+	return ids
+}
+
+func phaseID(phase int) string {
+	return fmt.Sprintf("phase%d", phase)
+}
+
+func phaseLabel(gp GraphPhase) string {
+	if gp.Approval {
+		return fmt.Sprintf("Phase %d (approval)", gp.Phase)
+	}
+	return fmt.Sprintf("Phase %d", gp.Phase)
+}
+
+// nodeID returns an identifier valid in both dot and mermaid
+func nodeID(phase int, node GraphNode) string {
+	id := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, node.Name)
+	return fmt.Sprintf("p%d_%s", phase, id)
+}
+
+// nodeLabel returns the label lines of a node: the app, its group and its version
+func nodeLabel(node GraphNode) []string {
+	lines := []string{node.Name}
+	if node.Group != "" {
+		lines = append(lines, "group: "+node.Group)
+	}
+	switch {
+	case node.Version != "":
+		lines = append(lines, "version: "+node.Version)
+	case node.Error != "":
+		lines = append(lines, "version: unresolved")
+	case node.Constraint != "":
+		lines = append(lines, "version: "+node.Constraint)
+	}
+	return lines
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
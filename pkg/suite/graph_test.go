@@ -0,0 +1,72 @@
+package suite
+
+import (
+	"bytes"
+	"encoding/json"
+	"qtm/pkg/catalog"
+	"strings"
+	"testing"
+)
+
+// TestBuildGraph tests dependency and phase edges, versions and each format
+func TestBuildGraph(t *testing.T) {
+	s := Suite{
+		Name:   "demo",
+		Phases: []PhaseConfig{{Phase: 2, Approval: ApprovalRequired}},
+		Items: []SuiteItem{
+			{Name: "app1-phase1", RolloutPhase: 1},
+			{Name: "app2-phase2", RolloutPhase: 2, DependsOn: []string{"app1-phase1"}},
+			{Name: "missing", RolloutPhase: 2},
+		},
+	}
+
+	graph := BuildGraph(s, catalog.NewMockCatalogSource())
+	if len(graph.Phases) != 2 || !graph.Phases[1].Approval {
+		t.Fatalf("Phases = %+v", graph.Phases)
+	}
+	if got := graph.Phases[1].Nodes[0]; got.Version != "2.2.2" {
+		t.Errorf("app2-phase2 version = %q, want 2.2.2", got.Version)
+	}
+	if got := graph.Phases[1].Nodes[1]; got.Version != "" || got.Error == "" {
+		t.Errorf("Expected an unresolved version for missing: %+v", got)
+	}
+	if len(graph.Edges) != 1 || graph.Edges[0] != (GraphEdge{From: "app1-phase1", To: "app2-phase2", Kind: "dependency"}) {
+		t.Errorf("Edges = %+v", graph.Edges)
+	}
+
+	var out bytes.Buffer
+	if err := WriteGraph(&out, graph, GraphDot); err != nil {
+		t.Fatalf("WriteGraph dot error = %v", err)
+	}
+	if !strings.Contains(out.String(), `"p1_app1_phase1" -> "p2_app2_phase2";`) {
+		t.Errorf("dot output missing dependency edge:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := WriteGraph(&out, graph, GraphMermaid); err != nil {
+		t.Fatalf("WriteGraph mermaid error = %v", err)
+	}
+	if !strings.Contains(out.String(), `p2_app2_phase2["app2-phase2<br/>version: 2.2.2"]`) {
+		t.Errorf("mermaid output missing annotated node:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := WriteGraph(&out, graph, GraphJSON); err != nil {
+		t.Fatalf("WriteGraph json error = %v", err)
+	}
+	var decoded Graph
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded.Suite != "demo" {
+		t.Errorf("json output = %s, error = %v", out.String(), err)
+	}
+
+	if err := WriteGraph(&out, graph, "svg"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+
+	// Without dependencies consecutive phases are linked
+	s.Items[1].DependsOn = nil
+	graph = BuildGraph(s, nil)
+	if len(graph.Edges) != 1 || graph.Edges[0] != (GraphEdge{From: "phase1", To: "phase2", Kind: "phase"}) {
+		t.Errorf("Edges = %+v", graph.Edges)
+	}
+}
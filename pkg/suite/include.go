@@ -0,0 +1,231 @@
+package suite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"qtm/pkg/values"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// IncludeConfig adds the items of another suite, read from a file or stored
+// in etcd, to a suite
+type IncludeConfig struct {
+	File        string                  `yaml:"file,omitempty"`  // Path of a suite file, relative to the including file
+	Suite       string                  `yaml:"suite,omitempty"` // Name of a suite stored in etcd
+	PhaseOffset int                     `yaml:"phaseOffset,omitempty"`
+	Overrides   map[string]ItemOverride `yaml:"overrides,omitempty"` // Settings changed on included items, keyed by app name
+}
+
+// ItemOverride changes the settings of an included item. Unset fields keep the
+// value of the included item, values are merged over it.
+type ItemOverride struct {
+	Group         string           `yaml:"group,omitempty"`
+	Version       string           `yaml:"version,omitempty"`
+	Timeout       time.Duration    `yaml:"timeout,omitempty"`
+	Retries       *int             `yaml:"retries,omitempty"`
+	values.Source `yaml:",inline"` // Helm values merged over those of the item
+}
+
+// IncludeLoader reads included suites. Suites named by etcd key can only be
+// included when the loader has a client.
+type IncludeLoader struct {
+	Client *clientv3.Client
+	Prefix string
+}
+
+func NewIncludeLoader(client *clientv3.Client, prefix string) *IncludeLoader {
+	return &IncludeLoader{
+		Client: client,
+		Prefix: prefix,
+	}
+}
+
+// FileOrigin returns the origin of a suite file for ResolveIncludes. The path
+// is made absolute so the same file always has the same origin.
+func FileOrigin(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "file:" + path
+}
+
+// load reads an included suite, returning it with an ID that is unique to its
+// document for cycle detection
+func (l *IncludeLoader) load(include IncludeConfig, origin string) (Suite, string, error) {
+	switch {
+	case include.File != "" && include.Suite != "":
+		return Suite{}, "", fmt.Errorf("include sets both file %s and suite %s", include.File, include.Suite)
+	case include.File != "":
+		path := include.File
+		if !filepath.IsAbs(path) && strings.HasPrefix(origin, "file:") {
+			path = filepath.Join(filepath.Dir(strings.TrimPrefix(origin, "file:")), path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Suite{}, "", err
+		}
+		s, err := ParseSuite(data)
+		if err != nil {
+			return Suite{}, "", fmt.Errorf("%s: %w", path, err)
+		}
+		return s, FileOrigin(path), nil
+	case include.Suite != "":
+		if l == nil || l.Client == nil {
+			return Suite{}, "", fmt.Errorf("cannot include suite %s without an etcd connection", include.Suite)
+		}
+		data, err := NewRemoteSuiteSource(l.Client, include.Suite, l.Prefix).FetchDocument()
+		if err != nil {
+			return Suite{}, "", err
+		}
+		s, err := ParseSuite(data)
+		if err != nil {
+			return Suite{}, "", fmt.Errorf("suite %s: %w", include.Suite, err)
+		}
+		return s, "suite:" + include.Suite, nil
+	}
+	return Suite{}, "", fmt.Errorf("include must set a file or a suite")
+}
+
+// ResolveIncludes flattens a suite, replacing its includes with the items,
+// phase settings and suite hooks of the included suites. Included hooks run
+// before the suite's own hooks at the same point. Includes are resolved recursively,
+// the origin names the suite document ("file:<path>" or "suite:<name>") so
+// relative paths and include cycles can be found.
+func ResolveIncludes(s Suite, origin string, loader *IncludeLoader) (Suite, error) {
+	resolved, _, err := resolveIncludes(s, origin, loader, []string{origin})
+	return resolved, err
+}
+
+// resolveIncludes flattens a suite and reports, for every resulting item, the
+// index of the include it came from, or -1 for the suite's own items
+func resolveIncludes(s Suite, origin string, loader *IncludeLoader, stack []string) (Suite, []int, error) {
+	if len(s.Includes) == 0 {
+		return s, ownItems(s.Items), nil
+	}
+
+	flat := s
+	flat.Includes = nil
+	flat.Items = nil
+	flat.Phases = nil
+	flat.Hooks = nil
+	var sources []int
+
+	phases := make(map[int]int) // phase to index in flat.Phases
+	addPhase := func(pc PhaseConfig) {
+		if i, ok := phases[pc.Phase]; ok {
+			flat.Phases[i] = pc
+			return
+		}
+		phases[pc.Phase] = len(flat.Phases)
+		flat.Phases = append(flat.Phases, pc)
+	}
+
+	for i, include := range s.Includes {
+		included, id, err := loader.load(include, origin)
+		if err != nil {
+			return Suite{}, nil, fmt.Errorf("include %d of %s: %w", i+1, displayOrigin(origin), err)
+		}
+		for _, seen := range stack {
+			if seen == id {
+				chain := append(append([]string(nil), stack...), id)
+				for j := range chain {
+					chain[j] = displayOrigin(chain[j])
+				}
+				return Suite{}, nil, fmt.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+			}
+		}
+
+		included, _, err = resolveIncludes(included, id, loader, append(stack, id))
+		if err != nil {
+			return Suite{}, nil, err
+		}
+
+		remaining := make(map[string]bool, len(include.Overrides))
+		for name := range include.Overrides {
+			remaining[name] = true
+		}
+		for _, item := range included.Items {
+			item.RolloutPhase += include.PhaseOffset
+			item.Source = mergeSources(included.Source, item.Source)
+			if override, ok := include.Overrides[item.Name]; ok {
+				item = override.apply(item)
+				delete(remaining, item.Name)
+			}
+			flat.Items = append(flat.Items, item)
+			sources = append(sources, i)
+		}
+		if len(remaining) > 0 {
+			var names []string
+			for name := range remaining {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return Suite{}, nil, fmt.Errorf("include %d of %s overrides %s, which it does not include", i+1, displayOrigin(origin), strings.Join(names, ", "))
+		}
+
+		for _, pc := range included.Phases {
+			pc.Phase += include.PhaseOffset
+			addPhase(pc)
+		}
+		flat.Hooks = append(flat.Hooks, included.Hooks...)
+	}
+
+	// The including suite's own items and phase settings come last and win
+	for _, pc := range s.Phases {
+		addPhase(pc)
+	}
+	flat.Hooks = append(flat.Hooks, s.Hooks...)
+	flat.Items = append(flat.Items, s.Items...)
+	sources = append(sources, ownItems(s.Items)...)
+	return flat, sources, nil
+}
+
+// apply returns the item with the override's settings
+func (o ItemOverride) apply(item SuiteItem) SuiteItem {
+	if o.Group != "" {
+		item.Group = o.Group
+	}
+	if o.Version != "" {
+		item.Version = o.Version
+	}
+	if o.Timeout != 0 {
+		item.Timeout = o.Timeout
+	}
+	if o.Retries != nil {
+		retries := *o.Retries
+		item.Retries = &retries
+	}
+	item.Source = mergeSources(item.Source, o.Source)
+	return item
+}
+
+// mergeSources layers override values over base values
+func mergeSources(base, override values.Source) values.Source {
+	if base.IsEmpty() {
+		return override
+	}
+	if override.IsEmpty() {
+		return base
+	}
+	return values.Source{
+		Files:  append(append([]string(nil), base.Files...), override.Files...),
+		Values: values.Merge(base.Values, override.Values),
+	}
+}
+
+func ownItems(items []SuiteItem) []int {
+	sources := make([]int, len(items))
+	for i := range sources {
+		sources[i] = -1
+	}
+	return sources
+}
+
+func displayOrigin(origin string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(origin, "file:"), "suite:")
+}
@@ -0,0 +1,138 @@
+package suite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSuites(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestResolveIncludes tests phase offsets, overrides and nested includes
+func TestResolveIncludes(t *testing.T) {
+	dir := writeSuites(t, map[string]string{
+		"base.yaml": `
+- name: dns
+  group: platform
+  rolloutPhase: 1
+`,
+		"platform.yaml": `
+apiVersion: qtm/v2
+name: platform
+defaults:
+  group: platform
+hooks:
+  - name: warm-cache
+    when: after
+    type: command
+    command: [true]
+includes:
+  - file: base.yaml
+phases:
+  - phase: 2
+    approval: required
+items:
+  - name: ingress
+    rolloutPhase: 2
+`,
+		"payments.yaml": `
+apiVersion: qtm/v2
+name: payments
+includes:
+  - file: platform.yaml
+    phaseOffset: 1
+    overrides:
+      ingress:
+        version: ^2
+        values:
+          replicas: 3
+items:
+  - name: api
+    group: payments
+    rolloutPhase: 4
+`,
+	})
+
+	source, err := NewFileSuiteSource(filepath.Join(dir, "payments.yaml"))
+	if err != nil {
+		t.Fatalf("NewFileSuiteSource error = %v", err)
+	}
+	s, err := source.FetchSuite()
+	if err != nil {
+		t.Fatalf("FetchSuite error = %v", err)
+	}
+
+	var got []string
+	for _, item := range s.Items {
+		got = append(got, fmt.Sprintf("%s@%d", item.Name, item.RolloutPhase))
+	}
+	if strings.Join(got, " ") != "dns@2 ingress@3 api@4" {
+		t.Errorf("Items = %v, want dns@2 ingress@3 api@4", got)
+	}
+	ingress := s.Items[1]
+	if ingress.Version != "^2" || ingress.Values["replicas"] != 3 || ingress.Group != "platform" {
+		t.Errorf("Override not applied to ingress: %+v", ingress)
+	}
+	if len(s.Hooks) != 1 || s.Hooks[0].Name != "warm-cache" {
+		t.Errorf("Hooks = %+v, want the included warm-cache hook", s.Hooks)
+	}
+	if !s.PhaseConfigs()[3].RequiresApproval() || len(s.Includes) != 0 || s.Name != "payments" {
+		t.Errorf("Resolved suite = %+v", s)
+	}
+}
+
+// TestResolveIncludesErrors tests include cycles and overrides of missing items
+func TestResolveIncludesErrors(t *testing.T) {
+	dir := writeSuites(t, map[string]string{
+		"a.yaml":     "includes:\n  - file: b.yaml\nitems: []\n",
+		"b.yaml":     "includes:\n  - file: a.yaml\nitems: []\n",
+		"over.yaml":  "includes:\n  - file: other.yaml\n    overrides:\n      web: {version: '1.0.0'}\nitems: []\n",
+		"other.yaml": "- {name: api, group: core, rolloutPhase: 1}\n",
+		"etcd.yaml":  "includes:\n  - suite: platform\nitems: []\n",
+	})
+
+	// Suite files named by a relative path find a cycle back to themselves
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	relative, err := NewFileSuiteSource("a.yaml")
+	if err != nil {
+		t.Fatalf("NewFileSuiteSource error = %v", err)
+	}
+	abs, _ := filepath.Abs("a.yaml")
+	absB, _ := filepath.Abs("b.yaml")
+	if _, err := relative.FetchSuite(); err == nil || !strings.HasSuffix(err.Error(), abs+" -> "+absB+" -> "+abs) {
+		t.Errorf("FetchSuite error = %v, want a cycle from %s back to itself", err, abs)
+	}
+
+	tests := map[string]string{
+		"a.yaml":    "include cycle",
+		"over.yaml": "overrides web",
+		"etcd.yaml": "without an etcd connection",
+	}
+	for file, want := range tests {
+		source, err := NewFileSuiteSource(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("NewFileSuiteSource(%s) error = %v", file, err)
+		}
+		if _, err := source.FetchSuite(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("FetchSuite(%s) error = %v, want %q", file, err, want)
+		}
+	}
+}
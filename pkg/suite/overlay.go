@@ -0,0 +1,184 @@
+package suite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"qtm/pkg/catalog"
+	"qtm/pkg/values"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Overlay changes a base suite for one environment, for example:
+//
+//	namespace: payments-prod
+//	remove: [debug-tools]
+//	patch:
+//	  api:
+//	    rolloutPhase: 2
+//	    retries: 2
+//	add:
+//	  - name: canary-checker
+//	    group: ops
+//	    rolloutPhase: 3
+//	phases:
+//	  - phase: 2
+//	    approval: required
+//
+// Changes are applied in the order remove, patch, add, phases, namespace and
+// values.
+type Overlay struct {
+	Namespace     string               `yaml:"namespace,omitempty"`
+	Remove        []string             `yaml:"remove,omitempty"` // Apps removed from every phase
+	Patch         map[string]ItemPatch `yaml:"patch,omitempty"`  // Settings changed on every item of an app
+	Add           []SuiteItem          `yaml:"add,omitempty"`
+	Phases        []PhaseConfig        `yaml:"phases,omitempty"` // Replace the settings of the same phase
+	values.Source `yaml:",inline"`     // Helm values merged over the suite values
+}
+
+// ItemPatch changes the settings of the items of an app
+type ItemPatch struct {
+	RolloutPhase *int `yaml:"rolloutPhase,omitempty"` // Set when the overlay moves the app, phase 0 included
+	ItemOverride `yaml:",inline"`
+}
+
+// ParseOverlay decodes an overlay document, refusing unknown fields
+func ParseOverlay(data []byte) (Overlay, error) {
+	var overlay Overlay
+	if err := yaml.UnmarshalStrict(data, &overlay); err != nil {
+		return Overlay{}, err
+	}
+	return overlay, nil
+}
+
+// ApplyOverlay returns the suite with the overlay's changes. Removing or
+// patching an app the suite does not have is an error, as are added items
+// and phases with settings ParseSuite would refuse.
+func ApplyOverlay(s Suite, overlay Overlay) (Suite, error) {
+	known := make(map[string]bool)
+	for _, item := range s.Items {
+		known[item.Name] = true
+	}
+	for _, name := range overlay.Remove {
+		if !known[name] {
+			return Suite{}, fmt.Errorf("overlay removes %s, which is not in suite %s", name, s.Name)
+		}
+	}
+	for name := range overlay.Patch {
+		if !known[name] {
+			return Suite{}, fmt.Errorf("overlay patches %s, which is not in suite %s", name, s.Name)
+		}
+	}
+
+	removed := make(map[string]bool, len(overlay.Remove))
+	for _, name := range overlay.Remove {
+		removed[name] = true
+	}
+
+	var items []SuiteItem
+	for _, item := range s.Items {
+		if removed[item.Name] {
+			continue
+		}
+		if patch, ok := overlay.Patch[item.Name]; ok {
+			item = patch.ItemOverride.apply(item)
+			if patch.RolloutPhase != nil {
+				item.RolloutPhase = *patch.RolloutPhase
+			}
+		}
+		items = append(items, item)
+	}
+
+	added := Suite{Defaults: s.Defaults, Items: append([]SuiteItem(nil), overlay.Add...)}
+	added.applyDefaults()
+	s.Items = append(items, added.Items...)
+
+	phases := append([]PhaseConfig(nil), s.Phases...)
+	for _, pc := range overlay.Phases {
+		replaced := false
+		for i := range phases {
+			if phases[i].Phase == pc.Phase {
+				phases[i], replaced = pc, true
+			}
+		}
+		if !replaced {
+			phases = append(phases, pc)
+		}
+	}
+	s.Phases = phases
+
+	if overlay.Namespace != "" {
+		s.Namespace = overlay.Namespace
+	}
+	s.Source = mergeSources(s.Source, overlay.Source)
+	if err := s.configErrors(); err != nil {
+		return Suite{}, err
+	}
+	return s, nil
+}
+
+// OverlaySource applies the overlay of an environment to the suite read from
+// another source
+type OverlaySource struct {
+	Base        SuiteSource
+	Environment string
+	Overlay     Overlay
+}
+
+func NewOverlaySource(base SuiteSource, env string, overlay Overlay) *OverlaySource {
+	return &OverlaySource{
+		Base:        base,
+		Environment: env,
+		Overlay:     overlay,
+	}
+}
+
+// NewEnvironmentSource reads the overlay of an environment for a file or etcd
+// suite. A suite file's overlays sit next to it as <name>.<env>.yaml, stored
+// suites keep them under <prefix>/suites/<name>/overlays/<env>.
+func NewEnvironmentSource(base SuiteSource, env string) (*OverlaySource, error) {
+	if err := catalog.ValidateEnvironment(env); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	var err error
+	switch source := base.(type) {
+	case *FileSource:
+		data, err = os.ReadFile(OverlayFile(source.Filename, env))
+	case *RemoteEtcdSource:
+		data, err = source.FetchOverlayDocument(env)
+	default:
+		return nil, fmt.Errorf("suite source %T does not support environment overlays", base)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay for environment %s: %w", env, err)
+	}
+
+	overlay, err := ParseOverlay(data)
+	if err != nil {
+		return nil, fmt.Errorf("overlay for environment %s: %w", env, err)
+	}
+	return NewOverlaySource(base, env, overlay), nil
+}
+
+// FetchSuite returns the base suite with the overlay applied
+func (ovs *OverlaySource) FetchSuite() (Suite, error) {
+	s, err := ovs.Base.FetchSuite()
+	if err != nil {
+		return Suite{}, err
+	}
+	s, err = ApplyOverlay(s, ovs.Overlay)
+	if err != nil {
+		return Suite{}, fmt.Errorf("environment %s: %w", ovs.Environment, err)
+	}
+	return s, nil
+}
+
+// OverlayFile returns the path of the overlay of an environment for a suite file
+func OverlayFile(suiteFile, env string) string {
+	ext := filepath.Ext(suiteFile)
+	return strings.TrimSuffix(suiteFile, ext) + "." + env + ext
+}
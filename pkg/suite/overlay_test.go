@@ -0,0 +1,140 @@
+package suite
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEnvironmentOverlay tests removing, patching and adding items from an
+// overlay file next to the suite
+func TestEnvironmentOverlay(t *testing.T) {
+	dir := writeSuites(t, map[string]string{
+		"payments.yaml": `
+apiVersion: qtm/v2
+name: payments
+namespace: payments
+defaults:
+  group: payments
+  retries: 1
+items:
+  - name: debug-tools
+    rolloutPhase: 1
+  - name: api
+    rolloutPhase: 1
+  - name: worker
+    rolloutPhase: 2
+  - name: migrations
+    rolloutPhase: 1
+`,
+		"payments.prod.yaml": `
+namespace: payments-prod
+remove: [debug-tools]
+patch:
+  api:
+    rolloutPhase: 2
+    version: 1.4.0
+  migrations:
+    rolloutPhase: 0
+add:
+  - name: canary-checker
+    rolloutPhase: 3
+phases:
+  - phase: 2
+    approval: required
+values:
+  replicas: 3
+`,
+	})
+
+	base, err := NewFileSuiteSource(filepath.Join(dir, "payments.yaml"))
+	if err != nil {
+		t.Fatalf("NewFileSuiteSource error = %v", err)
+	}
+	source, err := NewEnvironmentSource(base, "prod")
+	if err != nil {
+		t.Fatalf("NewEnvironmentSource error = %v", err)
+	}
+	s, err := source.FetchSuite()
+	if err != nil {
+		t.Fatalf("FetchSuite error = %v", err)
+	}
+
+	var got []string
+	for _, item := range s.Items {
+		got = append(got, fmt.Sprintf("%s@%d", item.Name, item.RolloutPhase))
+	}
+	if strings.Join(got, " ") != "api@2 worker@2 migrations@0 canary-checker@3" {
+		t.Errorf("Items = %v, want api@2 worker@2 migrations@0 canary-checker@3", got)
+	}
+	if s.Items[0].Version != "1.4.0" {
+		t.Errorf("api version = %q, want 1.4.0", s.Items[0].Version)
+	}
+	if added := s.Items[3]; added.Group != "payments" || added.RetryCount() != 1 {
+		t.Errorf("Suite defaults not applied to added item: %+v", added)
+	}
+	if s.Namespace != "payments-prod" || s.Values["replicas"] != 3 {
+		t.Errorf("Namespace = %q, values = %v", s.Namespace, s.Values)
+	}
+	if len(s.Phases) != 1 || s.Phases[0].Approval != "required" {
+		t.Errorf("Phases = %+v", s.Phases)
+	}
+
+	if _, err := NewEnvironmentSource(base, "staging"); err == nil {
+		t.Error("Expected an error for an environment without an overlay")
+	}
+	if _, err := NewEnvironmentSource(base, "../payments"); err == nil || !strings.Contains(err.Error(), "invalid environment") {
+		t.Errorf("NewEnvironmentSource error = %v, want invalid environment error", err)
+	}
+
+	_, err = ApplyOverlay(s, Overlay{Patch: map[string]ItemPatch{"missing": {}}})
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("ApplyOverlay error = %v, want unknown app error", err)
+	}
+}
+
+// TestOverlayChecks tests that hooks, checks and approvals added by an overlay
+// are refused like those of the suite, and that ValidateOverlay reports them
+func TestOverlayChecks(t *testing.T) {
+	dir := writeSuites(t, map[string]string{
+		"payments.yaml": `
+- name: api
+  group: payments
+  rolloutPhase: 1
+`,
+		"payments.prod.yaml": `
+add:
+  - name: smoke
+    group: payments
+    rolloutPhase: 2
+    hooks:
+      - name: notify
+        when: afterwards
+        type: http
+phases:
+  - phase: 2
+    approval: yes
+    checks:
+      - name: web
+        type: htpp
+`,
+	})
+
+	base, err := NewFileSuiteSource(filepath.Join(dir, "payments.yaml"))
+	if err != nil {
+		t.Fatalf("NewFileSuiteSource error = %v", err)
+	}
+	source, err := NewEnvironmentSource(base, "prod")
+	if err != nil {
+		t.Fatalf("NewEnvironmentSource error = %v", err)
+	}
+	if _, err := source.FetchSuite(); err == nil {
+		t.Fatal("Expected FetchSuite to refuse the overlay's hook, check and approval")
+	}
+
+	problems := ValidateOverlay(source, nil)
+	if len(problems) != 3 {
+		t.Errorf("ValidateOverlay problems = %v, want approval, check and hook problems", problems)
+	}
+}
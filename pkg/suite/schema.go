@@ -0,0 +1,129 @@
+package suite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// APIVersionV2 marks a suite document with metadata and defaults:
+//
+//	apiVersion: qtm/v2
+//	name: payments
+//	namespace: payments
+//	defaults:
+//	  group: core
+//	  timeout: 5m
+//	  retries: 1
+//	items:
+//	  - name: api
+//	    rolloutPhase: 1
+const APIVersionV2 = "qtm/v2"
+
+// SuiteDefaults are applied to every item that does not set its own value
+type SuiteDefaults struct {
+	Group   string        `yaml:"group,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Retries int           `yaml:"retries,omitempty"`
+}
+
+// RetryCount returns how many times a failed deployment of the item is retried
+func (item SuiteItem) RetryCount() int {
+	if item.Retries == nil {
+		return 0
+	}
+	return *item.Retries
+}
+
+// ParseSuite decodes a suite document. Versioned documents, the legacy
+// document with phases and items keys and the legacy bare list of items are
+// accepted. Unknown fields, unknown approvals, checks that cannot be built
+// and hooks that would never run are an error in every format.
+func ParseSuite(data []byte) (Suite, error) {
+	suite, err := parseSuite(data)
+	if err != nil {
+		return Suite{}, err
+	}
+	if err := suite.configErrors(); err != nil {
+		return Suite{}, err
+	}
+	return suite, nil
+}
+
+// parseSuite decodes a suite document without checking its phase settings and hooks
+func parseSuite(data []byte) (Suite, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Suite{}, err
+	}
+
+	var suite Suite
+	if _, isList := doc.([]interface{}); isList || doc == nil {
+		if err := yaml.UnmarshalStrict(data, &suite.Items); err != nil {
+			return Suite{}, err
+		}
+		return suite, nil
+	}
+
+	if err := yaml.UnmarshalStrict(data, &suite); err != nil {
+		return Suite{}, err
+	}
+
+	switch suite.APIVersion {
+	case "":
+		if suite.Namespace != "" || suite.Defaults != (SuiteDefaults{}) {
+			return Suite{}, fmt.Errorf("namespace and defaults require apiVersion %s", APIVersionV2)
+		}
+	case APIVersionV2:
+		if suite.Name == "" {
+			return Suite{}, fmt.Errorf("suite documents with apiVersion %s must have a name", APIVersionV2)
+		}
+		suite.applyDefaults()
+	default:
+		return Suite{}, fmt.Errorf("unsupported suite apiVersion %q, expected %s", suite.APIVersion, APIVersionV2)
+	}
+	return suite, nil
+}
+
+// parseSuiteLenient decodes as much of a suite document as it can, ignoring
+// unknown fields, values of the wrong type and the apiVersion. It fails only
+// when the document is not yaml.
+func parseSuiteLenient(data []byte) (Suite, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Suite{}, err
+	}
+
+	var suite Suite
+	var typeErr *yaml.TypeError
+	if _, isList := doc.([]interface{}); isList || doc == nil {
+		if err := yaml.Unmarshal(data, &suite.Items); err != nil && !errors.As(err, &typeErr) {
+			return Suite{}, err
+		}
+		return suite, nil
+	}
+	if err := yaml.Unmarshal(data, &suite); err != nil && !errors.As(err, &typeErr) {
+		return Suite{}, err
+	}
+	suite.applyDefaults()
+	return suite, nil
+}
+
+// applyDefaults fills in the items that leave a defaulted setting unset
+func (s *Suite) applyDefaults() {
+	for i := range s.Items {
+		item := &s.Items[i]
+		if item.Group == "" {
+			item.Group = s.Defaults.Group
+		}
+		if item.Timeout == 0 {
+			item.Timeout = s.Defaults.Timeout
+		}
+		if item.Retries == nil && s.Defaults.Retries != 0 {
+			retries := s.Defaults.Retries
+			item.Retries = &retries
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package suite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Requirement is a single condition on an item label
+type Requirement struct {
+	Key    string
+	Value  string
+	Negate bool // Match items whose label differs from Value, including items without it
+}
+
+// Matches reports whether the labels meet the requirement
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	if r.Negate {
+		return !ok || value != r.Value
+	}
+	return ok && value == r.Value
+}
+
+func (r Requirement) String() string {
+	if r.Negate {
+		return r.Key + "!=" + r.Value
+	}
+	return r.Key + "=" + r.Value
+}
+
+// Selector matches items whose labels meet every requirement
+type Selector []Requirement
+
+// ParseSelector parses a comma separated list of key=value and key!=value
+// requirements, for example team=payments,tier!=edge
+func ParseSelector(selector string) (Selector, error) {
+	var parsed Selector
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var req Requirement
+		if key, value, ok := strings.Cut(part, "!="); ok {
+			req = Requirement{Key: key, Value: value, Negate: true}
+		} else if key, value, ok := strings.Cut(part, "=="); ok {
+			req = Requirement{Key: key, Value: value}
+		} else if key, value, ok := strings.Cut(part, "="); ok {
+			req = Requirement{Key: key, Value: value}
+		} else {
+			return nil, fmt.Errorf("invalid selector requirement %q, expected key=value or key!=value", part)
+		}
+
+		req.Key, req.Value = strings.TrimSpace(req.Key), strings.TrimSpace(req.Value)
+		if req.Key == "" {
+			return nil, fmt.Errorf("invalid selector requirement %q, missing label key", part)
+		}
+		parsed = append(parsed, req)
+	}
+	return parsed, nil
+}
+
+// Matches reports whether the labels meet every requirement
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		if !req.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, req := range s {
+		parts[i] = req.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// ItemFilter picks the items of a suite to roll out. An empty filter keeps
+// every item.
+type ItemFilter struct {
+	Selector Selector
+	Only     []string // App names, every app when empty
+}
+
+// NewItemFilter parses the --selector and --only values given on the command line
+func NewItemFilter(selector string, only []string) (ItemFilter, error) {
+	parsed, err := ParseSelector(selector)
+	if err != nil {
+		return ItemFilter{}, err
+	}
+
+	var names []string
+	for _, name := range only {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return ItemFilter{Selector: parsed, Only: names}, nil
+}
+
+// IsEmpty reports whether the filter keeps every item
+func (f ItemFilter) IsEmpty() bool {
+	return len(f.Selector) == 0 && len(f.Only) == 0
+}
+
+// Matches reports whether the item is kept by the filter
+func (f ItemFilter) Matches(item SuiteItem) bool {
+	if len(f.Only) > 0 {
+		found := false
+		for _, name := range f.Only {
+			if name == item.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return f.Selector.Matches(item.Labels)
+}
+
+func (f ItemFilter) String() string {
+	var parts []string
+	if len(f.Selector) > 0 {
+		parts = append(parts, "selector="+f.Selector.String())
+	}
+	if len(f.Only) > 0 {
+		parts = append(parts, "only="+strings.Join(f.Only, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// AppNames returns the names of the apps in organized suite data, by phase
+func AppNames(phaseData map[int][]SuiteItem) []string {
+	var names []string
+	for _, phase := range SortedPhases(phaseData) {
+		for _, item := range phaseData[phase] {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// FilterPhases keeps the items of organized suite data that match the
+// filter, dropping phases left empty. Naming an app the suite does not have,
+// or leaving nothing to roll out, is an error.
+func FilterPhases(phaseData map[int][]SuiteItem, filter ItemFilter) (map[int][]SuiteItem, error) {
+	if filter.IsEmpty() {
+		return phaseData, nil
+	}
+
+	known := make(map[string]bool)
+	for _, items := range phaseData {
+		for _, item := range items {
+			known[item.Name] = true
+		}
+	}
+	var unknown []string
+	for _, name := range filter.Only {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("apps not in suite: %s", strings.Join(unknown, ", "))
+	}
+
+	filtered := make(map[int][]SuiteItem)
+	for phase, items := range phaseData {
+		for _, item := range items {
+			if filter.Matches(item) {
+				filtered[phase] = append(filtered[phase], item)
+			}
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no apps match %s", filter)
+	}
+	return filtered, nil
+}
@@ -0,0 +1,48 @@
+package suite
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFilterPhases tests picking apps by label selector and name
+func TestFilterPhases(t *testing.T) {
+	phaseData := OrganizeSuiteData(Suite{Items: []SuiteItem{
+		{Name: "gateway", RolloutPhase: 1, Labels: map[string]string{"team": "payments", "tier": "edge"}},
+		{Name: "api", RolloutPhase: 1, Labels: map[string]string{"team": "payments", "tier": "core"}},
+		{Name: "ledger", RolloutPhase: 2, Labels: map[string]string{"team": "payments"}},
+		{Name: "search", RolloutPhase: 3, Labels: map[string]string{"team": "discovery"}},
+	}})
+
+	tests := []struct {
+		name     string
+		selector string
+		only     []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "No filter", want: []string{"gateway", "api", "ledger", "search"}},
+		{name: "Selector", selector: "team=payments,tier!=edge", want: []string{"api", "ledger"}},
+		{name: "Only", only: []string{"search", "api"}, want: []string{"api", "search"}},
+		{name: "Selector and only", selector: "team==payments", only: []string{"ledger", "search"}, want: []string{"ledger"}},
+		{name: "Unknown app", only: []string{"billing"}, wantErr: true},
+		{name: "Nothing matches", selector: "team=ops", wantErr: true},
+		{name: "Invalid selector", selector: "team", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewItemFilter(tt.selector, tt.only)
+			if err == nil {
+				var filtered map[int][]SuiteItem
+				filtered, err = FilterPhases(phaseData, filter)
+				if err == nil && !reflect.DeepEqual(AppNames(filtered), tt.want) {
+					t.Errorf("Apps = %v, want %v", AppNames(filtered), tt.want)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
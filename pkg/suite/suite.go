@@ -1,16 +1,195 @@
 package suite
 
-import "sort"
+import (
+	"errors"
+	"fmt"
+	"qtm/pkg/values"
+	"sort"
+	"time"
+)
 
 type SuiteItem struct {
-	Name         string `yaml:"name"`
-	Group        string `yaml:"group"`
-	RolloutPhase int    `yaml:"rolloutPhase"`
+	Name          string            `yaml:"name"`
+	Group         string            `yaml:"group"`
+	RolloutPhase  int               `yaml:"rolloutPhase"`
+	Version       string            `yaml:"version,omitempty"`   // Exact version or semver constraint, the current catalog version when empty
+	Timeout       time.Duration     `yaml:"timeout,omitempty"`   // Limit on each deployment attempt, none when zero
+	Retries       *int              `yaml:"retries,omitempty"`   // Extra attempts after a failed or timed out deployment
+	DependsOn     []string          `yaml:"dependsOn,omitempty"` // Apps that must be deployed in an earlier phase
+	Labels        map[string]string `yaml:"labels,omitempty"`    // Matched by --selector to roll out part of a suite
+	Hooks         []HookConfig      `yaml:"hooks,omitempty"`
+	values.Source `yaml:",inline"`  // Helm values for this item only
 }
 
 type Suite struct {
-	Name  string
-	Items []SuiteItem `yaml:",inline"`
+	APIVersion    string           `yaml:"apiVersion,omitempty"`
+	Name          string           `yaml:"name"`
+	Namespace     string           `yaml:"namespace,omitempty"` // Namespace to deploy to unless one is given on the command line
+	Defaults      SuiteDefaults    `yaml:"defaults,omitempty"`
+	Includes      []IncludeConfig  `yaml:"includes,omitempty"` // Suites whose items are added to this suite
+	Hooks         []HookConfig     `yaml:"hooks"`
+	Phases        []PhaseConfig    `yaml:"phases"`
+	Items         []SuiteItem      `yaml:"items"`
+	values.Source `yaml:",inline"` // Helm values shared by every item
+}
+
+// ApprovalRequired marks a phase that may only start once a human has approved it
+const ApprovalRequired = "required"
+
+// PhaseConfig holds settings that apply to every item in a rollout phase
+type PhaseConfig struct {
+	Phase        int           `yaml:"phase"`
+	Approval     string        `yaml:"approval"`
+	Soak         time.Duration `yaml:"soak"`         // How long to keep verifying the phase once deployed
+	SoakInterval time.Duration `yaml:"soakInterval"` // How often checks are repeated during the soak
+	Checks       []CheckConfig `yaml:"checks"`
+	Hooks        []HookConfig  `yaml:"hooks"`
+}
+
+// Check types understood by the verify package
+const (
+	CheckRollout = "rollout"
+	CheckHTTP    = "http"
+	CheckCommand = "command"
+)
+
+// CheckConfig describes a health check run while a phase soaks
+type CheckConfig struct {
+	Name         string        `yaml:"name"`
+	Type         string        `yaml:"type"`
+	Namespace    string        `yaml:"namespace"`    // rollout: namespace of the deployment
+	Deployment   string        `yaml:"deployment"`   // rollout: name of the deployment to watch
+	Endpoint     string        `yaml:"endpoint"`     // http: session endpoint to probe, all endpoints when empty
+	Path         string        `yaml:"path"`         // http: path appended to the endpoint address
+	ExpectStatus int           `yaml:"expectStatus"` // http: expected status code, any 2xx when zero
+	Command      []string      `yaml:"command"`      // command: program and arguments, must exit zero
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// check reports a check that could not be built, it would fail the phase
+// only once the phase is deployed
+func (c CheckConfig) check() error {
+	name := c.Name
+	if name == "" {
+		name = c.Type
+	}
+	switch c.Type {
+	case CheckRollout:
+		if c.Deployment == "" {
+			return fmt.Errorf("check %s has no deployment", name)
+		}
+	case CheckHTTP:
+	case CheckCommand:
+		if len(c.Command) == 0 {
+			return fmt.Errorf("check %s has no command", name)
+		}
+	default:
+		return fmt.Errorf("check %s has an unknown type %q, expected %s, %s or %s", name, c.Type, CheckRollout, CheckHTTP, CheckCommand)
+	}
+	return nil
+}
+
+// Points in the lifecycle a hook can run at
+const (
+	HookBefore = "before"
+	HookAfter  = "after"
+)
+
+// Hook types understood by the hooks package
+const (
+	HookCommand = "command"
+	HookJob     = "job"
+	HookHTTP    = "http"
+)
+
+// HookConfig describes an action run before or after a suite, phase or app is deployed
+type HookConfig struct {
+	Name         string        `yaml:"name"`
+	When         string        `yaml:"when"` // before or after
+	Type         string        `yaml:"type"`
+	Command      []string      `yaml:"command"`      // command: program and arguments, must exit zero
+	Manifest     string        `yaml:"manifest"`     // job: inline Kubernetes Job manifest
+	ManifestFile string        `yaml:"manifestFile"` // job: path to a Kubernetes Job manifest
+	Namespace    string        `yaml:"namespace"`    // job: namespace to run the Job in
+	URL          string        `yaml:"url"`          // http: address to call
+	Method       string        `yaml:"method"`       // http: request method, GET when empty
+	Body         string        `yaml:"body"`         // http: request body
+	ExpectStatus int           `yaml:"expectStatus"` // http: expected status code, any 2xx when zero
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// check reports a hook whose when or type is not understood, it would never run
+func (h HookConfig) check() error {
+	var errs []error
+	if h.When != HookBefore && h.When != HookAfter {
+		errs = append(errs, fmt.Errorf("hook %s has an unknown when %q, expected %s or %s", h.Name, h.When, HookBefore, HookAfter))
+	}
+	if h.Type != HookCommand && h.Type != HookJob && h.Type != HookHTTP {
+		errs = append(errs, fmt.Errorf("hook %s has an unknown type %q, expected %s, %s or %s", h.Name, h.Type, HookCommand, HookJob, HookHTTP))
+	}
+	return errors.Join(errs...)
+}
+
+// check reports an approval other than ApprovalRequired, which would leave
+// the phase ungated
+func (pc PhaseConfig) check() error {
+	if pc.Approval != "" && pc.Approval != ApprovalRequired {
+		return fmt.Errorf("unknown approval %q, expected %s", pc.Approval, ApprovalRequired)
+	}
+	return nil
+}
+
+// configErrors checks the phase settings, the checks and the hooks of the
+// suite, its phases and its items
+func (s Suite) configErrors() error {
+	var errs []error
+	for _, hook := range s.Hooks {
+		if err := hook.check(); err != nil {
+			errs = append(errs, fmt.Errorf("suite: %w", err))
+		}
+	}
+	for _, pc := range s.Phases {
+		if err := pc.check(); err != nil {
+			errs = append(errs, fmt.Errorf("phase %d: %w", pc.Phase, err))
+		}
+		for _, check := range pc.Checks {
+			if err := check.check(); err != nil {
+				errs = append(errs, fmt.Errorf("phase %d: %w", pc.Phase, err))
+			}
+		}
+		for _, hook := range pc.Hooks {
+			if err := hook.check(); err != nil {
+				errs = append(errs, fmt.Errorf("phase %d: %w", pc.Phase, err))
+			}
+		}
+	}
+	for _, item := range s.Items {
+		for _, hook := range item.Hooks {
+			if err := hook.check(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", item.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NeedsVerification reports whether the phase has a soak period or checks to run
+func (pc PhaseConfig) NeedsVerification() bool {
+	return pc.Soak > 0 || len(pc.Checks) > 0
+}
+
+// RequiresApproval reports whether the phase is gated on a manual approval
+func (pc PhaseConfig) RequiresApproval() bool {
+	return pc.Approval == ApprovalRequired
+}
+
+// PhaseConfigs returns the phase settings of the suite keyed by phase
+func (s Suite) PhaseConfigs() map[int]PhaseConfig {
+	configs := make(map[int]PhaseConfig, len(s.Phases))
+	for _, pc := range s.Phases {
+		configs[pc.Phase] = pc
+	}
+	return configs
 }
 
 type SuiteSource interface {
@@ -42,6 +221,16 @@ func OrganizeSuiteData(s Suite) map[int][]SuiteItem {
 	return sortedPhaseData
 }
 
+// SortedPhases returns the phases of organized suite data in ascending order
+func SortedPhases(phaseData map[int][]SuiteItem) []int {
+	phases := make([]int, 0, len(phaseData))
+	for phase := range phaseData {
+		phases = append(phases, phase)
+	}
+	sort.Ints(phases)
+	return phases
+}
+
 // contains checks if an int slice contains a specific int
 func contains(slice []int, val int) bool {
 	for _, item := range slice {
@@ -1,8 +1,11 @@
 package suite
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // TestOrganizeSuiteData tests the OrganizeSuiteData function
@@ -56,3 +59,109 @@ func TestOrganizeSuiteData(t *testing.T) {
 		})
 	}
 }
+
+// TestParseSuite tests that both the legacy list and the phased document formats are read
+func TestParseSuite(t *testing.T) {
+	legacy := []byte(`
+- name: app1
+  group: test
+  rolloutPhase: 1
+`)
+	document := []byte(`
+phases:
+  - phase: 2
+    approval: required
+items:
+  - name: app1
+    group: test
+    rolloutPhase: 1
+`)
+
+	s, err := ParseSuite(legacy)
+	if err != nil {
+		t.Fatalf("ParseSuite(legacy) error = %v", err)
+	}
+	if len(s.Items) != 1 || len(s.Phases) != 0 {
+		t.Errorf("ParseSuite(legacy) = %+v", s)
+	}
+
+	s, err = ParseSuite(document)
+	if err != nil {
+		t.Fatalf("ParseSuite(document) error = %v", err)
+	}
+	if len(s.Items) != 1 || !s.PhaseConfigs()[2].RequiresApproval() {
+		t.Errorf("ParseSuite(document) = %+v", s)
+	}
+}
+
+// TestParseSuiteV2 tests versioned documents, their defaults and strict decoding
+func TestParseSuiteV2(t *testing.T) {
+	document := []byte(`
+apiVersion: qtm/v2
+name: payments
+namespace: payments
+defaults:
+  group: core
+  timeout: 5m
+  retries: 2
+items:
+  - name: api
+    rolloutPhase: 1
+  - name: worker
+    group: jobs
+    rolloutPhase: 2
+    timeout: 30s
+    retries: 0
+`)
+
+	s, err := ParseSuite(document)
+	if err != nil {
+		t.Fatalf("ParseSuite(v2) error = %v", err)
+	}
+	if s.Name != "payments" || s.Namespace != "payments" {
+		t.Errorf("ParseSuite(v2) metadata = %q %q", s.Name, s.Namespace)
+	}
+	api, worker := s.Items[0], s.Items[1]
+	if api.Group != "core" || api.Timeout != 5*time.Minute || api.RetryCount() != 2 {
+		t.Errorf("Defaults not applied to api: %+v", api)
+	}
+	if worker.Group != "jobs" || worker.Timeout != 30*time.Second || worker.RetryCount() != 0 {
+		t.Errorf("Item settings overridden by defaults for worker: %+v", worker)
+	}
+
+	invalid := map[string]string{
+		"unknown field":       "apiVersion: qtm/v2\nname: x\nitems: []\nowner: me\n",
+		"unknown item field":  "apiVersion: qtm/v2\nname: x\nitems:\n  - name: api\n    phase: 1\n",
+		"legacy unknown":      "- name: api\n  rolloutPhas: 1\n",
+		"missing name":        "apiVersion: qtm/v2\nitems: []\n",
+		"unsupported version": "apiVersion: qtm/v9\nname: x\n",
+		"v2 field without v2": "name: x\ndefaults:\n  group: core\n",
+	}
+	for name, doc := range invalid {
+		if _, err := ParseSuite([]byte(doc)); err == nil {
+			t.Errorf("ParseSuite(%s) expected an error", name)
+		}
+	}
+}
+
+// TestFileSuiteSourceName tests that unnamed suites are named after their file
+func TestFileSuiteSourceName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkout.yaml")
+	if err := os.WriteFile(path, []byte("- name: app1\n  group: test\n  rolloutPhase: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewFileSuiteSource(path)
+	if err != nil {
+		t.Fatalf("NewFileSuiteSource error = %v", err)
+	}
+	s, err := source.FetchSuite()
+	if err != nil || s.Name != "checkout" {
+		t.Errorf("FetchSuite() = %q, %v, want checkout", s.Name, err)
+	}
+
+	source.SuiteName = "payments"
+	if _, err := source.FetchSuite(); err == nil {
+		t.Errorf("Expected an error fetching a suite the file does not define")
+	}
+}
@@ -0,0 +1,400 @@
+package suite
+
+import (
+	"errors"
+	"fmt"
+	"qtm/pkg/catalog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a mistake found in a suite document
+type Problem struct {
+	Line    int // Line in the document, zero when unknown
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// lineRe finds the line number in yaml decoding errors
+var lineRe = regexp.MustCompile(`line (\d+): `)
+
+// Validate checks a suite document and returns every problem found, ordered by
+// line. Includes are resolved as ResolveIncludes does, and problems with
+// included items are reported on the line of their include. Items are only
+// looked up in the catalog when one is given. A document that does not match
+// the schema is still checked as far as it can be decoded.
+func Validate(data []byte, origin string, loader *IncludeLoader, catalogSource catalog.CatalogSource) []Problem {
+	var problems []Problem
+	s, err := parseSuite(data)
+	if err != nil {
+		problems = schemaProblems(err)
+		if s, err = parseSuiteLenient(data); err != nil {
+			return problems
+		}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return schemaProblems(err)
+	}
+	lines := nodeLines(&root)
+	ownPhases := s.Phases
+
+	s, sources, err := resolveIncludes(s, origin, loader, []string{origin})
+	if err != nil {
+		return append(problems, Problem{Message: err.Error()})
+	}
+	lines.flatten(sources)
+
+	add := func(line int, format string, args ...interface{}) {
+		problems = append(problems, Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Items: required fields, pins and duplicates
+	seen := make(map[string]int)
+	byName := make(map[string][]int)
+	for i, item := range s.Items {
+		line := lines.item(i)
+		if item.Name == "" {
+			add(line, "item has no name")
+			continue
+		}
+		label := fmt.Sprintf("%s (phase %d)", item.Name, item.RolloutPhase)
+		if item.Group == "" {
+			add(line, "%s has no group", label)
+		}
+		if item.Version != "" {
+			if _, err := semver.NewConstraint(item.Version); err != nil {
+				add(line, "%s has an invalid version %q: %v", label, item.Version, err)
+			}
+		}
+		key := fmt.Sprintf("%s@%d", item.Name, item.RolloutPhase)
+		if first, ok := seen[key]; ok {
+			add(line, "%s is a duplicate of the item on line %d", label, lines.item(first))
+		} else {
+			seen[key] = i
+		}
+		byName[item.Name] = append(byName[item.Name], i)
+	}
+
+	// Phases: gaps between phases and settings for phases without items
+	phases := SortedPhases(OrganizeSuiteData(s))
+	for i := 1; i < len(phases); i++ {
+		if phases[i] != phases[i-1]+1 {
+			add(0, "no items in phase %d, phases jump from %d to %d", phases[i-1]+1, phases[i-1], phases[i])
+		}
+	}
+	ownPhaseLine := func(phase int) int {
+		for i, own := range ownPhases {
+			if own.Phase == phase {
+				return lines.phase(i)
+			}
+		}
+		return 0
+	}
+	for _, pc := range s.Phases {
+		if !contains(phases, pc.Phase) {
+			add(ownPhaseLine(pc.Phase), "settings for phase %d, which has no items", pc.Phase)
+		}
+	}
+
+	// Phase settings: unknown approval and checks that cannot be built
+	for _, pc := range s.Phases {
+		if err := pc.check(); err != nil {
+			add(ownPhaseLine(pc.Phase), "phase %d has an %v", pc.Phase, err)
+		}
+		for _, check := range pc.Checks {
+			if err := check.check(); err != nil {
+				add(ownPhaseLine(pc.Phase), "phase %d %v", pc.Phase, err)
+			}
+		}
+	}
+
+	// Hooks: unknown when or type
+	for i, hook := range s.Hooks {
+		if err := hook.check(); err != nil {
+			add(lines.hook(i), "%v", strings.ReplaceAll(err.Error(), "\n", "; "))
+		}
+	}
+	for _, pc := range s.Phases {
+		for _, hook := range pc.Hooks {
+			if err := hook.check(); err != nil {
+				add(ownPhaseLine(pc.Phase), "phase %d %v", pc.Phase, strings.ReplaceAll(err.Error(), "\n", "; "))
+			}
+		}
+	}
+	for i, item := range s.Items {
+		for _, hook := range item.Hooks {
+			if err := hook.check(); err != nil {
+				add(lines.item(i), "%s %v", item.Name, strings.ReplaceAll(err.Error(), "\n", "; "))
+			}
+		}
+	}
+
+	// Dependencies: unknown apps, ordering and cycles
+	for i, item := range s.Items {
+		for _, dep := range item.DependsOn {
+			indexes, ok := byName[dep]
+			switch {
+			case !ok:
+				add(lines.item(i), "%s depends on %s, which is not in the suite", item.Name, dep)
+			case dep == item.Name:
+				add(lines.item(i), "%s depends on itself", item.Name)
+			case !deployedBefore(s.Items, indexes, item.RolloutPhase):
+				add(lines.item(i), "%s in phase %d depends on %s, which is not deployed in an earlier phase", item.Name, item.RolloutPhase, dep)
+			}
+		}
+	}
+	for _, cycle := range dependencyCycles(s.Items) {
+		add(lines.item(byName[cycle[0]][0]), "dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	// Catalog: every item must resolve
+	if catalogSource != nil {
+		for i, item := range s.Items {
+			if item.Name == "" {
+				continue
+			}
+			if _, err := catalogSource.FetchData(item.Name, item.Group, item.Version); err != nil {
+				add(lines.item(i), "%s cannot be resolved in the catalog: %v", item.Name, err)
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(a, b int) bool { return problems[a].Line < problems[b].Line })
+	return problems
+}
+
+// ValidateOverlay checks that the overlay of an environment applies to its
+// suite, and that the items it adds or changes resolve in the catalog when
+// one is given. Problems of the suite itself are left to Validate.
+func ValidateOverlay(source *OverlaySource, catalogSource catalog.CatalogSource) []Problem {
+	base, err := source.Base.FetchSuite()
+	if err != nil {
+		return nil
+	}
+
+	var problems []Problem
+	s, err := ApplyOverlay(base, source.Overlay)
+	if err != nil {
+		for _, message := range strings.Split(err.Error(), "\n") {
+			problems = append(problems, Problem{Message: message})
+		}
+		return problems
+	}
+	if catalogSource == nil {
+		return nil
+	}
+
+	lookup := func(item SuiteItem) string {
+		return fmt.Sprintf("%s/%s@%s", item.Group, item.Name, item.Version)
+	}
+	unchanged := make(map[string]bool, len(base.Items))
+	for _, item := range base.Items {
+		unchanged[lookup(item)] = true
+	}
+	for _, item := range s.Items {
+		if item.Name == "" || unchanged[lookup(item)] {
+			continue
+		}
+		if _, err := catalogSource.FetchData(item.Name, item.Group, item.Version); err != nil {
+			problems = append(problems, Problem{Message: fmt.Sprintf("%s cannot be resolved in the catalog: %v", item.Name, err)})
+		}
+	}
+	return problems
+}
+
+// schemaProblems splits a decoding error into one problem per field
+func schemaProblems(err error) []Problem {
+	messages := []string{err.Error()}
+	var typeErr *yamlv2.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	var problems []Problem
+	for _, message := range messages {
+		problem := Problem{Message: message}
+		if match := lineRe.FindStringSubmatchIndex(message); match != nil {
+			problem.Line, _ = strconv.Atoi(message[match[2]:match[3]])
+			problem.Message = strings.TrimPrefix(message[:match[0]]+message[match[1]:], "yaml: ")
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// documentLines holds the lines of the items, phase settings and includes of
+// a document
+type documentLines struct {
+	items    []int
+	phases   []int
+	includes []int
+	hooks    []int // Suite level hooks
+}
+
+// flatten maps the lines of the document's own items onto the items of the
+// flattened suite, giving included items the line of their include
+func (dl *documentLines) flatten(sources []int) {
+	own := dl.items
+	dl.items = make([]int, len(sources))
+	next := 0
+	for i, source := range sources {
+		switch {
+		case source >= 0 && source < len(dl.includes):
+			dl.items[i] = dl.includes[source]
+		case source < 0 && next < len(own):
+			dl.items[i] = own[next]
+			next++
+		}
+	}
+}
+
+func (dl documentLines) item(i int) int {
+	if i < len(dl.items) {
+		return dl.items[i]
+	}
+	return 0
+}
+
+func (dl documentLines) hook(i int) int {
+	if i < len(dl.hooks) {
+		return dl.hooks[i]
+	}
+	return 0
+}
+
+func (dl documentLines) phase(i int) int {
+	if i < len(dl.phases) {
+		return dl.phases[i]
+	}
+	return 0
+}
+
+// nodeLines finds the line of every item and phase setting in either the
+// bare list or the document format
+func nodeLines(root *yaml.Node) documentLines {
+	var dl documentLines
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return dl
+	}
+
+	doc := root.Content[0]
+	var items, phases, includes, hooks *yaml.Node
+	switch doc.Kind {
+	case yaml.SequenceNode:
+		items = doc
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(doc.Content); i += 2 {
+			switch doc.Content[i].Value {
+			case "items":
+				items = doc.Content[i+1]
+			case "phases":
+				phases = doc.Content[i+1]
+			case "includes":
+				includes = doc.Content[i+1]
+			case "hooks":
+				hooks = doc.Content[i+1]
+			}
+		}
+	}
+
+	if items != nil {
+		for _, node := range items.Content {
+			dl.items = append(dl.items, node.Line)
+		}
+	}
+	if phases != nil {
+		for _, node := range phases.Content {
+			dl.phases = append(dl.phases, node.Line)
+		}
+	}
+	if includes != nil {
+		for _, node := range includes.Content {
+			dl.includes = append(dl.includes, node.Line)
+		}
+	}
+	if hooks != nil {
+		for _, node := range hooks.Content {
+			dl.hooks = append(dl.hooks, node.Line)
+		}
+	}
+	return dl
+}
+
+// deployedBefore reports whether any of the items is deployed before the phase
+func deployedBefore(items []SuiteItem, indexes []int, phase int) bool {
+	for _, i := range indexes {
+		if items[i].RolloutPhase < phase {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyCycles returns every cycle in the dependencies between apps, each
+// starting and ending with the same app
+func dependencyCycles(items []SuiteItem) [][]string {
+	deps := make(map[string][]string)
+	var names []string
+	for _, item := range items {
+		if _, ok := deps[item.Name]; !ok {
+			names = append(names, item.Name)
+		}
+		deps[item.Name] = append(deps[item.Name], item.DependsOn...)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range deps[name] {
+			if _, known := deps[dep]; !known || dep == name {
+				continue
+			}
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == dep {
+						cycle := append(append([]string(nil), stack[i:]...), dep)
+						cycles = append(cycles, cycle)
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return cycles
+}
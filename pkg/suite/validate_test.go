@@ -0,0 +1,104 @@
+package suite
+
+import (
+	"qtm/pkg/catalog"
+	"reflect"
+	"testing"
+)
+
+// TestValidate tests that every problem in a suite is reported with its line
+func TestValidate(t *testing.T) {
+	document := []byte(`apiVersion: qtm/v2
+name: payments
+phases:
+  - phase: 5
+    approval: required
+items:
+  - name: app1
+    group: test
+    rolloutPhase: 1
+  - name: app1
+    group: test
+    rolloutPhase: 1
+  - name: app2
+    rolloutPhase: 1
+    dependsOn: [app3]
+  - name: app3
+    group: test
+    rolloutPhase: 3
+    dependsOn: [app2, db]
+  - name: missing
+    group: test
+    rolloutPhase: 3
+`)
+
+	got := Validate(document, "test", nil, catalog.NewMockCatalogSource(catalog.WithNormalBehavior()))
+	var lines []int
+	for _, problem := range got {
+		lines = append(lines, problem.Line)
+	}
+
+	// Catalog lookups fail for every app not named like the mock entries
+	want := []int{
+		0,  // no items in phase 2
+		4,  // settings for phase 5
+		7,  // app1 not in the catalog
+		10, // duplicate app1
+		10, // app1 not in the catalog
+		13, // app2 has no group
+		13, // app2 depends on a later phase
+		13, // dependency cycle
+		13, // app2 not in the catalog
+		16, // app3 depends on db
+		16, // app3 not in the catalog
+		20, // missing not in the catalog
+	}
+	if !reflect.DeepEqual(lines, want) {
+		for _, problem := range got {
+			t.Log(problem)
+		}
+		t.Errorf("Problem lines = %v, want %v", lines, want)
+	}
+
+	schema := Validate([]byte("- name: app1\n  group: test\n  rolloutPhase: 1\n- name: app2\n  phase: 2\n  owner: me\n"), "test", nil, nil)
+	if len(schema) != 3 || schema[0].Line != 4 || schema[1].Line != 5 || schema[2].Line != 6 {
+		t.Errorf("Schema problems = %v, want no group on line 4 and unknown fields on lines 5 and 6", schema)
+	}
+
+	// Structural checks still run when the document does not match the schema
+	mixed := Validate([]byte("apiVersion: qtm/v2\nname: mixed\nitems:\n  - name: app1\n    group: test\n    rolloutPhase: 1\n    owner: me\n  - name: app1\n    group: test\n    rolloutPhase: 1\n"), "test", nil, nil)
+	if len(mixed) != 2 || mixed[0].Line != 7 || mixed[1].Line != 8 {
+		t.Errorf("Problems = %v, want unknown field on line 7 and duplicate on line 8", mixed)
+	}
+
+	// Hooks that would never run are refused when parsing and reported on their line
+	hooked := []byte("name: hooked\nhooks:\n  - name: notify\n    when: befor\n    type: http\nitems:\n  - name: app1\n    group: test\n    rolloutPhase: 1\n    hooks:\n      - name: migrate\n        when: before\n        type: script\n")
+	if _, err := ParseSuite(hooked); err == nil {
+		t.Errorf("Expected ParseSuite to refuse unknown hook when and type")
+	}
+	if problems := Validate(hooked, "test", nil, nil); len(problems) != 2 || problems[0].Line != 3 || problems[1].Line != 7 {
+		t.Errorf("Hook problems = %v, want problems on lines 3 and 7", problems)
+	}
+
+	// Approvals other than required would leave the phase ungated
+	gated := []byte("name: gated\nphases:\n  - phase: 1\n    approval: Required\nitems:\n  - name: app1\n    group: test\n    rolloutPhase: 1\n")
+	if _, err := ParseSuite(gated); err == nil {
+		t.Errorf("Expected ParseSuite to refuse an unknown approval")
+	}
+	if problems := Validate(gated, "test", nil, nil); len(problems) != 1 || problems[0].Line != 3 {
+		t.Errorf("Approval problems = %v, want a problem on line 3", problems)
+	}
+
+	// Checks that cannot be built would fail the phase only after deploying it
+	checked := []byte("name: checked\nphases:\n  - phase: 1\n    checks:\n      - name: web\n        type: htpp\n  - phase: 2\n    checks:\n      - type: rollout\nitems:\n  - name: app1\n    group: test\n    rolloutPhase: 1\n  - name: app2\n    group: test\n    rolloutPhase: 2\n")
+	if _, err := ParseSuite(checked); err == nil {
+		t.Errorf("Expected ParseSuite to refuse unknown check types and missing deployments")
+	}
+	if problems := Validate(checked, "test", nil, nil); len(problems) != 2 || problems[0].Line != 3 || problems[1].Line != 7 {
+		t.Errorf("Check problems = %v, want problems on lines 3 and 7", problems)
+	}
+
+	if problems := Validate([]byte("- name: app1\n  group: test\n  rolloutPhase: 1\n"), "test", nil, nil); len(problems) != 0 {
+		t.Errorf("Expected a valid suite, got %v", problems)
+	}
+}
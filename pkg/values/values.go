@@ -0,0 +1,174 @@
+// Package values resolves the Helm values passed to each deployed app.
+//
+// Values may be set at four levels. Later levels take precedence over
+// earlier ones:
+//
+//  1. catalog: the values of the catalog entry of the app
+//  2. suite: values shared by every item of the suite
+//  3. suite item: values of a single item of the suite
+//  4. environment: values given to the rollout, e.g. with --values
+//
+// Within a level, values files are merged in the order they are listed and
+// inline values are merged last. Maps are merged key by key, any other value
+// replaces the one below it, and a null value removes the key entirely. The
+// chart's own values.yaml sits below all of these levels and is merged by Helm.
+package values
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Values is a tree of Helm values
+type Values map[string]interface{}
+
+// UnmarshalYAML decodes values with string keys at every level so they can
+// be passed to Helm and encoded as JSON
+func (v *Values) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw map[interface{}]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	*v = normalize(raw).(map[string]interface{})
+	return nil
+}
+
+// Source is a set of values files and inline values at one level
+type Source struct {
+	Files  []string `yaml:"valuesFiles,omitempty" json:"valuesFiles,omitempty"`
+	Values Values   `yaml:"values,omitempty" json:"values,omitempty"`
+}
+
+// IsEmpty reports whether the source sets no values
+func (s Source) IsEmpty() bool {
+	return len(s.Files) == 0 && len(s.Values) == 0
+}
+
+// Load reads the values files of the source and merges its inline values on top
+func (s Source) Load() (Values, error) {
+	merged := Values{}
+	for _, file := range s.Files {
+		loaded, err := ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		merged = Merge(merged, loaded)
+	}
+	return Merge(merged, s.Values), nil
+}
+
+// Resolve loads every source and merges them, later sources taking precedence
+func Resolve(sources ...Source) (Values, error) {
+	merged := Values{}
+	for _, source := range sources {
+		loaded, err := source.Load()
+		if err != nil {
+			return nil, err
+		}
+		merged = Merge(merged, loaded)
+	}
+	return merged, nil
+}
+
+// Layers holds the values that apply to every app of a rollout
+type Layers struct {
+	Suite       Source
+	Environment Source
+}
+
+// For merges the values of an app in precedence order: catalog, suite, suite
+// item and environment
+func (l Layers) For(catalogValues, itemValues Source) (Values, error) {
+	return Resolve(catalogValues, l.Suite, itemValues, l.Environment)
+}
+
+// ReadFile reads a YAML values file
+func ReadFile(path string) (Values, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	var values Values
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file %s: %w", path, err)
+	}
+	if values == nil {
+		values = Values{}
+	}
+	return values, nil
+}
+
+// Merge deep merges override on top of base and returns the result. Neither
+// argument is modified.
+func Merge(base, override Values) Values {
+	merged := make(Values, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+
+		baseMap, baseIsMap := asMap(merged[key])
+		overrideMap, overrideIsMap := asMap(value)
+		if baseIsMap && overrideIsMap {
+			merged[key] = map[string]interface{}(Merge(baseMap, overrideMap))
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// Digest returns a SHA-256 digest of the values that does not depend on key order
+func Digest(values Values) string {
+	if len(values) == 0 {
+		values = Values{}
+	}
+	// encoding/json sorts map keys, which makes the encoding stable
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func asMap(value interface{}) (Values, bool) {
+	switch m := value.(type) {
+	case Values:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	}
+	return nil, false
+}
+
+// normalize converts the maps decoded by yaml.v2 into maps with string keys
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalize(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalize(item)
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,78 @@
+package values
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestMerge(t *testing.T) {
+	base := Values{
+		"image":    map[string]interface{}{"repository": "web", "tag": "1.0"},
+		"replicas": 2,
+		"debug":    true,
+	}
+	override := Values{
+		"image":    map[string]interface{}{"tag": "1.1"},
+		"replicas": 3,
+		"debug":    nil,
+	}
+
+	merged := Merge(base, override)
+
+	image := merged["image"].(map[string]interface{})
+	if image["repository"] != "web" || image["tag"] != "1.1" {
+		t.Errorf("image = %v, want repository web and tag 1.1", image)
+	}
+	if merged["replicas"] != 3 {
+		t.Errorf("replicas = %v, want 3", merged["replicas"])
+	}
+	if _, ok := merged["debug"]; ok {
+		t.Errorf("Expected null to remove debug")
+	}
+	if base["image"].(map[string]interface{})["tag"] != "1.0" {
+		t.Errorf("Merge modified its base")
+	}
+}
+
+func TestLayersPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "prod.yaml")
+	if err := os.WriteFile(envFile, []byte("env: prod\nlevel: environment\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var item Source
+	if err := yaml.Unmarshal([]byte("values:\n  level: item\n  resources:\n    limits:\n      cpu: 1\n"), &item); err != nil {
+		t.Fatalf("Failed to parse item values: %v", err)
+	}
+
+	layers := Layers{
+		Suite:       Source{Values: Values{"level": "suite", "suite": true}},
+		Environment: Source{Files: []string{envFile}},
+	}
+	catalog := Source{Values: Values{"level": "catalog", "catalog": true}}
+
+	merged, err := layers.For(catalog, item)
+	if err != nil {
+		t.Fatalf("For failed: %v", err)
+	}
+
+	expected := map[string]interface{}{"level": "environment", "env": "prod", "suite": true, "catalog": true}
+	for key, want := range expected {
+		if merged[key] != want {
+			t.Errorf("%s = %v, want %v", key, merged[key], want)
+		}
+	}
+
+	// Values decoded from YAML must be usable as JSON for the session
+	if _, err := json.Marshal(merged); err != nil {
+		t.Errorf("Merged values are not JSON encodable: %v", err)
+	}
+	if Digest(merged) != Digest(Merge(Values{}, merged)) {
+		t.Errorf("Digest is not stable")
+	}
+}
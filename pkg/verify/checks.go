@@ -0,0 +1,127 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RolloutStatusCheck passes once a Kubernetes deployment has fully rolled out,
+// mirroring `kubectl rollout status`
+type RolloutStatusCheck struct {
+	CheckName  string
+	Client     kubernetes.Interface
+	Namespace  string
+	Deployment string
+}
+
+func (c *RolloutStatusCheck) Name() string {
+	return c.CheckName
+}
+
+func (c *RolloutStatusCheck) Run(ctx context.Context) error {
+	deployment, err := c.Client.AppsV1().Deployments(c.Namespace).Get(ctx, c.Deployment, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	switch {
+	case status.ObservedGeneration < deployment.Generation:
+		return fmt.Errorf("deployment %s/%s spec update not yet observed", c.Namespace, c.Deployment)
+	case status.UpdatedReplicas < replicas:
+		return fmt.Errorf("deployment %s/%s has %d of %d replicas updated", c.Namespace, c.Deployment, status.UpdatedReplicas, replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		return fmt.Errorf("deployment %s/%s has %d old replicas pending termination", c.Namespace, c.Deployment, status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return fmt.Errorf("deployment %s/%s has %d of %d updated replicas available", c.Namespace, c.Deployment, status.AvailableReplicas, status.UpdatedReplicas)
+	}
+	return nil
+}
+
+// HTTPCheck probes a URL and expects a given status code, or any 2xx status
+type HTTPCheck struct {
+	CheckName    string
+	Client       *http.Client
+	URL          string
+	ExpectStatus int
+}
+
+func (c *HTTPCheck) Name() string {
+	return c.CheckName
+}
+
+func (c *HTTPCheck) Run(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if c.ExpectStatus != 0 {
+		if resp.StatusCode != c.ExpectStatus {
+			return fmt.Errorf("GET %s returned %d, expected %d", c.URL, resp.StatusCode, c.ExpectStatus)
+		}
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s returned %d", c.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// CommandCheck runs a local command which must exit zero
+type CommandCheck struct {
+	CheckName string
+	Command   []string
+}
+
+func (c *CommandCheck) Name() string {
+	return c.CheckName
+}
+
+func (c *CommandCheck) Run(ctx context.Context) error {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(c.Command, " "), err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// endpointURL builds a probe URL from an endpoint address recorded in the session
+func endpointURL(address, path string) string {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	if path == "" {
+		return address
+	}
+	return strings.TrimSuffix(address, "/") + "/" + strings.TrimPrefix(path, "/")
+}
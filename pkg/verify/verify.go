@@ -0,0 +1,182 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"qtm/pkg/suite"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultSoakInterval is used when a phase soaks without an explicit interval
+const DefaultSoakInterval = 10 * time.Second
+
+// Check is a single health verification run against a deployed phase
+type Check interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// Builder turns check configuration from a suite into runnable checks
+type Builder struct {
+	Kube             kubernetes.Interface // Client used by rollout checks, may be nil if none are configured
+	HTTPClient       *http.Client
+	DefaultNamespace string
+}
+
+// NewBuilder creates a Builder with a default HTTP client
+func NewBuilder(kube kubernetes.Interface, namespace string) *Builder {
+	return &Builder{
+		Kube:             kube,
+		HTTPClient:       &http.Client{Timeout: 10 * time.Second},
+		DefaultNamespace: namespace,
+	}
+}
+
+// Build creates the checks described by configs. HTTP checks probe the given
+// session endpoints.
+func (b *Builder) Build(configs []suite.CheckConfig, endpoints map[string]string) ([]Check, error) {
+	var checks []Check
+	for i, cfg := range configs {
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", cfg.Type, i)
+		}
+
+		var check Check
+		switch cfg.Type {
+		case suite.CheckRollout:
+			if b.Kube == nil {
+				return nil, fmt.Errorf("check %s: no kubernetes client configured", name)
+			}
+			if cfg.Deployment == "" {
+				return nil, fmt.Errorf("check %s: deployment is required", name)
+			}
+			namespace := cfg.Namespace
+			if namespace == "" {
+				namespace = b.DefaultNamespace
+			}
+			check = &RolloutStatusCheck{CheckName: name, Client: b.Kube, Namespace: namespace, Deployment: cfg.Deployment}
+		case suite.CheckHTTP:
+			httpChecks, err := b.buildHTTPChecks(name, cfg, endpoints)
+			if err != nil {
+				return nil, err
+			}
+			checks = append(checks, httpChecks...)
+			continue
+		case suite.CheckCommand:
+			if len(cfg.Command) == 0 {
+				return nil, fmt.Errorf("check %s: command is required", name)
+			}
+			check = &CommandCheck{CheckName: name, Command: cfg.Command}
+		default:
+			return nil, fmt.Errorf("check %s: unknown check type %q", name, cfg.Type)
+		}
+
+		if cfg.Timeout > 0 {
+			check = &timeoutCheck{Check: check, timeout: cfg.Timeout}
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+func (b *Builder) buildHTTPChecks(name string, cfg suite.CheckConfig, endpoints map[string]string) ([]Check, error) {
+	targets := make(map[string]string)
+	if cfg.Endpoint != "" {
+		address, ok := endpoints[cfg.Endpoint]
+		if !ok {
+			return nil, fmt.Errorf("check %s: endpoint %s is not recorded in the session", name, cfg.Endpoint)
+		}
+		targets[cfg.Endpoint] = address
+	} else {
+		targets = endpoints
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("check %s: no endpoints recorded in the session", name)
+	}
+
+	endpointNames := make([]string, 0, len(targets))
+	for endpointName := range targets {
+		endpointNames = append(endpointNames, endpointName)
+	}
+	sort.Strings(endpointNames)
+
+	var checks []Check
+	for _, endpointName := range endpointNames {
+		var check Check = &HTTPCheck{
+			CheckName:    fmt.Sprintf("%s/%s", name, endpointName),
+			Client:       b.HTTPClient,
+			URL:          endpointURL(targets[endpointName], cfg.Path),
+			ExpectStatus: cfg.ExpectStatus,
+		}
+		if cfg.Timeout > 0 {
+			check = &timeoutCheck{Check: check, timeout: cfg.Timeout}
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// Soak runs every check immediately and then once per interval until the
+// duration has elapsed. The first failing check ends the soak with an error.
+func Soak(ctx context.Context, duration, interval time.Duration, checks []Check, logger *zap.Logger) error {
+	if interval <= 0 {
+		interval = DefaultSoakInterval
+	}
+
+	deadline := time.Now().Add(duration)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := RunChecks(ctx, checks, logger); err != nil {
+			return err
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil
+		}
+
+		remaining := time.NewTimer(time.Until(deadline))
+		select {
+		case <-ctx.Done():
+			remaining.Stop()
+			return ctx.Err()
+		case <-remaining.C:
+		case <-ticker.C:
+			remaining.Stop()
+		}
+	}
+}
+
+// RunChecks runs each check once, returning the first failure
+func RunChecks(ctx context.Context, checks []Check, logger *zap.Logger) error {
+	for _, check := range checks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := check.Run(ctx); err != nil {
+			logger.Error("Verification check failed", zap.String("check", check.Name()), zap.Error(err))
+			return fmt.Errorf("check %s failed: %w", check.Name(), err)
+		}
+		logger.Debug("Verification check passed", zap.String("check", check.Name()))
+	}
+	return nil
+}
+
+// timeoutCheck bounds each run of the wrapped check
+type timeoutCheck struct {
+	Check
+	timeout time.Duration
+}
+
+func (t *timeoutCheck) Run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.Check.Run(ctx)
+}
@@ -0,0 +1,122 @@
+package verify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"qtm/pkg/suite"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRolloutStatusCheck(t *testing.T) {
+	replicas := int32(2)
+	tests := []struct {
+		name      string
+		status    appsv1.DeploymentStatus
+		expectErr bool
+	}{
+		{
+			name:   "Rolled out",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		{
+			name:      "Replicas still updating",
+			status:    appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1},
+			expectErr: true,
+		},
+		{
+			name:      "Generation not observed",
+			status:    appsv1.DeploymentStatus{ObservedGeneration: 0, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kube := fake.NewSimpleClientset(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod", Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status:     tt.status,
+			})
+
+			checks, err := NewBuilder(kube, "prod").Build([]suite.CheckConfig{{Type: suite.CheckRollout, Deployment: "api"}}, nil)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			err = checks[0].Run(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Run() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckUsesSessionEndpoints(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer healthy.Close()
+
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	builder := NewBuilder(nil, "")
+	endpoints := map[string]string{"api": healthy.URL, "worker": unhealthy.URL}
+
+	checks, err := builder.Build([]suite.CheckConfig{{Type: suite.CheckHTTP, Endpoint: "api", Path: "/healthz"}}, endpoints)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if err := RunChecks(context.Background(), checks, zap.NewNop()); err != nil {
+		t.Errorf("Expected healthy endpoint to pass, got %v", err)
+	}
+
+	checks, err = builder.Build([]suite.CheckConfig{{Type: suite.CheckHTTP, Path: "/healthz"}}, endpoints)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("Expected a check per endpoint, got %d", len(checks))
+	}
+	if err := RunChecks(context.Background(), checks, zap.NewNop()); err == nil {
+		t.Errorf("Expected unhealthy endpoint to fail")
+	}
+
+	if _, err := builder.Build([]suite.CheckConfig{{Type: suite.CheckHTTP, Endpoint: "missing"}}, endpoints); err == nil {
+		t.Errorf("Expected an error for an endpoint missing from the session")
+	}
+}
+
+func TestSoak(t *testing.T) {
+	passing := []Check{&CommandCheck{CheckName: "true", Command: []string{"true"}}}
+	failing := []Check{&CommandCheck{CheckName: "false", Command: []string{"false"}}}
+
+	start := time.Now()
+	if err := Soak(context.Background(), 50*time.Millisecond, 10*time.Millisecond, passing, zap.NewNop()); err != nil {
+		t.Errorf("Soak() with passing checks error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Soak() returned after %v, before the soak period ended", elapsed)
+	}
+
+	if err := Soak(context.Background(), time.Second, 10*time.Millisecond, failing, zap.NewNop()); err == nil {
+		t.Errorf("Expected Soak() to fail with a failing check")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Soak(ctx, time.Second, 10*time.Millisecond, passing, zap.NewNop()); err != context.Canceled {
+		t.Errorf("Soak() with cancelled context error = %v", err)
+	}
+}